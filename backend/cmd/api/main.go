@@ -2,55 +2,194 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"goapi/internal/auth"
 	"goapi/internal/config"
 	"goapi/internal/database"
+	"goapi/internal/features"
 	"goapi/internal/handlers"
+	"goapi/internal/health"
+	"goapi/internal/mailer"
+	"goapi/internal/metrics"
+	"goapi/internal/ratelimit"
 	"goapi/internal/middleware"
+	"goapi/internal/models"
 	"goapi/internal/services"
+	"goapi/internal/tracing"
 	"goapi/pkg/logger"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	// Initialize logger
-	logger := logger.NewLogger()
-	logger.Info("Starting Go API Server...")
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
+	// Load configuration: defaults, then the config file, environment variables and flags
+	var opts config.Options
+	if err := config.NewFlagSet(os.Args[0], &opts).Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(opts)
+	if err != nil {
+		logger.NewLogger().Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger; the level can change when configuration is reloaded
+	logLevel := new(slog.LevelVar)
+	log, err := logger.New(logger.Options{Level: cfg.Logging.Level, Format: cfg.Logging.Format, LevelVar: logLevel})
+	if err != nil {
+		logger.NewLogger().Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("Starting Go API Server...", "environment", cfg.Server.Environment)
+
+	// Initialize CORS from configuration
+	corsMiddleware, err := middleware.NewCORS(cfg.CORS)
+	if err != nil {
+		log.Error("Invalid CORS configuration", "error", err)
+		os.Exit(1)
+	}
+	cors := middleware.NewReloadable(corsMiddleware)
+
+	// Initialize feature flags
+	featureFlags := features.NewFlags(cfg.Features.Enabled)
+
+	// Initialize security headers from configuration
+	securityHeaders, err := middleware.NewSecurityHeaders(cfg.Security)
+	if err != nil {
+		log.Error("Invalid security configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize panic reporting
+	panicReporter, err := middleware.NewPanicReporter(cfg.Panic)
+	if err != nil {
+		log.Error("Invalid panic reporter configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
 
-	// Load configuration
-	cfg := config.LoadConfig()
-	logger.Info("Configuration loaded successfully")
+	// Initialize metrics
+	appMetrics := metrics.NewMetrics()
+
+	// Secrets held by a file or vault provider are re-read so rotated database passwords apply
+	var dbSecrets config.SecretProvider
+	if cfg.Secrets.Provider != "env" {
+		if dbSecrets, err = config.NewSecretProvider(cfg.Secrets, opts.EnvPrefix); err != nil {
+			log.Error("Invalid secrets configuration", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// Initialize database
-	db, err := database.NewDatabase(cfg)
+	db, err := database.NewDatabase(cfg, log.With("component", "database"), appMetrics, dbSecrets)
 	if err != nil {
-		logger.Error("Failed to initialize database: %v", err)
+		log.Error("Failed to initialize database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
 	// Initialize repositories
 	userRepo := database.NewUserRepository(db)
+	authRepo := database.NewAuthRepository(db)
+	orgRepo := database.NewOrganizationRepository(db)
+	inviteRepo := database.NewInvitationRepository(db)
+	lockoutRepo := database.NewLockoutRepository(db)
+	auditRepo := database.NewAuditRepository(db)
+
+	// Cache user lookups in front of the user repository
+	var userStore services.UserStore = userRepo
+	if cfg.UserCache.Enabled {
+		userCache, err := database.NewCachedUserRepository(userRepo, cfg.UserCache, log.With("component", "user_cache"), appMetrics)
+		if err != nil {
+			log.Error("Failed to initialize user cache", "error", err)
+			os.Exit(1)
+		}
+		defer userCache.Close()
+		userStore = userCache
+	}
+
+	// Initialize token manager
+	tokens := auth.NewTokenManager(cfg.Auth.TokenSecret)
+
+	// Initialize mailer
+	mail, err := mailer.NewMailer(cfg.Mail, log.With("component", "mailer"))
+	if err != nil {
+		log.Error("Failed to initialize mailer", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize services
-	userService := services.NewUserService(userRepo)
+	accountService := services.NewAccountService(userStore, authRepo, tokens, mail, cfg.Auth, cfg.Mail)
+	userService := services.NewUserService(userStore, accountService, log.With("component", "user_service"))
+	orgService := services.NewOrganizationService(orgRepo)
+	invitationService := services.NewInvitationService(inviteRepo, orgRepo, userStore, userService, tokens, mail, cfg.Auth, cfg.Mail, log.With("component", "invitation_service"))
+	loginGuard := services.NewLoginGuard(lockoutRepo, auditRepo, cfg.Lockout, log.With("component", "login_guard"))
+	authService := services.NewAuthService(userStore, authRepo, tokens, loginGuard, cfg.Auth)
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
+	authHandler := handlers.NewAuthHandler(authService, orgService)
+	accountHandler := handlers.NewAccountHandler(accountService, orgService)
+	orgHandler := handlers.NewOrganizationHandler(orgService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
 	testHandler := handlers.NewTestHandler()
 
+	// Register readiness checks
+	healthRegistry := health.NewRegistry(
+		time.Duration(cfg.Health.CheckTimeout)*time.Second,
+		time.Duration(cfg.Health.CacheTTL)*time.Second,
+	)
+	healthRegistry.Register("database", health.CheckerFunc(db.CheckConnection))
+	healthRegistry.Register("migrations", health.CheckerFunc(db.CheckMigrations))
+	healthRegistry.Register("connection_pool", health.CheckerFunc(db.CheckPoolSaturation(cfg.Health.SaturationThreshold)))
+	healthHandler := handlers.NewHealthHandler(healthRegistry)
+
+	// Initialize rate limiter
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter, err = newRateLimiter(cfg.RateLimit, db, log.With("component", "rate_limit"))
+		if err != nil {
+			log.Error("Failed to initialize rate limiting", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// Setup routes
-	router := setupRoutes(userHandler,testHandler)
+	router := setupRoutes(userHandler, authHandler, accountHandler, orgHandler, invitationHandler, healthHandler, testHandler, appMetrics)
+
+	// Initialize per-route Cache-Control policies from configuration
+	cacheControlMiddleware, err := middleware.NewCacheControl(cfg.CacheControl, router)
+	if err != nil {
+		log.Error("Invalid cache control configuration", "error", err)
+		os.Exit(1)
+	}
+	cacheControl := middleware.NewReloadable(cacheControlMiddleware)
 
 	// Setup middleware
-	handler := setupMiddleware(router, cfg)
+	handler := setupMiddleware(router, cfg, log, appMetrics, limiter, cors.Wrap, cacheControl.Wrap, securityHeaders, panicReporter, tokens, orgService)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -58,13 +197,20 @@ func main() {
 		Handler:      handler,
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		ErrorLog:     slog.NewLogLogger(log.Slog().Handler(), slog.LevelError),
 	}
 
+	// Reload runtime settings on SIGHUP and config file changes
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	watcher := config.NewWatcher(opts, cfg, reloadRuntimeConfig(logLevel, cors, cacheControl, router, limiter, featureFlags), log.With("component", "config"))
+	go watcher.Run(watchCtx)
+
 	// Start server in a goroutine
 	go func() {
-		logger.Info("Server starting on %s:%s", cfg.Server.Host, cfg.Server.Port)
+		log.Info("Server starting", "addr", server.Addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Failed to start server: %v", err)
+			log.Error("Failed to start server", "error", err)
 			os.Exit(1)
 		}
 	}()
@@ -73,61 +219,196 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	logger.Info("Server is shutting down...")
+	log.Info("Server is shutting down...")
+
+	// Fail readiness first so load balancers stop sending traffic before connections drain
+	healthRegistry.SetShuttingDown()
+	time.Sleep(time.Duration(cfg.Server.ShutdownDelay) * time.Second)
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("Server forced to shutdown: %v", err)
+		log.Error("Server forced to shutdown", "error", err)
 		os.Exit(1)
 	}
 
-	logger.Info("Server exited")
+	// Flush buffered spans before exiting
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("Failed to shut down tracing", "error", err)
+	}
+
+	log.Info("Server exited")
 }
 
 // setupRoutes configures all API routes
-func setupRoutes(userHandler *handlers.UserHandler, testHandler *handlers.TestHandler) *mux.Router {
+func setupRoutes(userHandler *handlers.UserHandler, authHandler *handlers.AuthHandler, accountHandler *handlers.AccountHandler, orgHandler *handlers.OrganizationHandler, invitationHandler *handlers.InvitationHandler, healthHandler *handlers.HealthHandler, testHandler *handlers.TestHandler, appMetrics *metrics.Metrics) *mux.Router {
 	router := mux.NewRouter()
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
 	
-	// User routes
-	api.HandleFunc("/users", userHandler.GetUsers).Methods("GET")
-	api.HandleFunc("/users/{id}", userHandler.GetUser).Methods("GET")
-	api.HandleFunc("/users", userHandler.CreateUser).Methods("POST")
-	api.HandleFunc("/users/{id}", userHandler.UpdateUser).Methods("PUT")
-	api.HandleFunc("/users/{id}", userHandler.DeleteUser).Methods("DELETE")
+	// User routes are always scoped to the caller's organization
+	users := api.PathPrefix("/users").Subrouter()
+	users.Use(middleware.RequireTenant)
+	users.HandleFunc("", userHandler.GetUsers).Methods("GET")
+	users.HandleFunc("/{id}", userHandler.GetUser).Methods("GET")
+	users.HandleFunc("", userHandler.CreateUser).Methods("POST")
+	// Users may change or delete their own account; anyone else's needs an admin
+	selfOrAdmin := middleware.RequireSelfOrRole(models.RoleAdmin, models.RoleSuperAdmin)
+	users.Handle("/{id}", selfOrAdmin(http.HandlerFunc(userHandler.UpdateUser))).Methods("PUT")
+	users.Handle("/{id}", selfOrAdmin(http.HandlerFunc(userHandler.DeleteUser))).Methods("DELETE")
+	users.Handle("/{id}/unlock", middleware.RequireRole(models.RoleAdmin, models.RoleSuperAdmin)(http.HandlerFunc(authHandler.UnlockUser))).Methods("POST")
+
+	// Invitation acceptance is public; the signed token identifies the organization
+	api.HandleFunc("/invitations/{token}/accept", invitationHandler.AcceptInvitation).Methods("POST")
+
+	// Invitation management is limited to admins of the caller's organization
+	invitations := api.PathPrefix("/invitations").Subrouter()
+	invitations.Use(middleware.RequireTenant, middleware.RequireRole(models.RoleAdmin, models.RoleSuperAdmin))
+	invitations.HandleFunc("", invitationHandler.GetInvitations).Methods("GET")
+	invitations.HandleFunc("", invitationHandler.CreateInvitation).Methods("POST")
+	invitations.HandleFunc("/{id}", invitationHandler.RevokeInvitation).Methods("DELETE")
+
+	// Organization routes are limited to platform super admins
+	orgs := api.PathPrefix("/organizations").Subrouter()
+	orgs.Use(middleware.RequireRole(models.RoleSuperAdmin))
+	orgs.HandleFunc("", orgHandler.GetOrganizations).Methods("GET")
+	orgs.HandleFunc("/{id}", orgHandler.GetOrganization).Methods("GET")
+	orgs.HandleFunc("", orgHandler.CreateOrganization).Methods("POST")
+	orgs.HandleFunc("/{id}", orgHandler.UpdateOrganization).Methods("PUT")
+	orgs.HandleFunc("/{id}", orgHandler.DeleteOrganization).Methods("DELETE")
+
+	// Auth routes
+	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+	api.HandleFunc("/auth/mfa/verify", authHandler.VerifyMFA).Methods("POST")
+
+	// MFA enrollment accepts the restricted token issued to users who must enroll before logging in
+	enrollment := middleware.RequireAuth(auth.TokenTypeAccess, auth.TokenTypeMFAEnrollment)
+	api.Handle("/auth/mfa/enroll", enrollment(http.HandlerFunc(authHandler.EnrollMFA))).Methods("POST")
+	api.Handle("/auth/mfa/qr", enrollment(http.HandlerFunc(authHandler.MFAQRCode))).Methods("GET")
+	api.Handle("/auth/mfa/confirm", enrollment(http.HandlerFunc(authHandler.ConfirmMFA))).Methods("POST")
+	api.Handle("/auth/mfa/recovery-codes", middleware.RequireAuth()(http.HandlerFunc(authHandler.RegenerateRecoveryCodes))).Methods("POST")
+
+	// Account routes
+	api.HandleFunc("/auth/verify-email", accountHandler.VerifyEmail).Methods("POST")
+	api.Handle("/auth/verify-email/resend", middleware.RequireAuth()(http.HandlerFunc(accountHandler.ResendVerification))).Methods("POST")
+	api.HandleFunc("/auth/password/forgot", accountHandler.ForgotPassword).Methods("POST")
+	api.HandleFunc("/auth/password/reset", accountHandler.ResetPassword).Methods("POST")
 
 
 
 	api.HandleFunc("/test", testHandler.Test).Methods("GET")
 
-	// Health check endpoint
-	router.HandleFunc("/health", healthCheck).Methods("GET")
+	// Health check endpoints; /health is kept as an alias of /readyz
+	router.HandleFunc("/livez", healthHandler.Livez).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Readyz).Methods("GET")
+	router.HandleFunc("/health", healthHandler.Readyz).Methods("GET")
+
+	// Prometheus metrics endpoint
+	router.Handle("/metrics", appMetrics.Handler()).Methods("GET")
 
 	return router
 }
 
 // setupMiddleware configures all middleware
-func setupMiddleware(router *mux.Router, cfg *config.Config) http.Handler {
-	// Recovery middleware (should be first)
-	handler := middleware.RecoveryMiddleware(router)
-	
+func setupMiddleware(router *mux.Router, cfg *config.Config, log logger.Logger, appMetrics *metrics.Metrics, limiter *ratelimit.Limiter, corsMiddleware, cacheControl, securityHeaders func(http.Handler) http.Handler, panicReporter middleware.PanicReporter, tokens *auth.TokenManager, tenants middleware.TenantResolver) http.Handler {
+	// Tenant middleware scopes the request to an organization
+	handler := middleware.Tenant(tenants, cfg.IsDevelopment())(router)
+
+	// Read consistency sessions keep a user's reads on the primary right after their writes
+	handler = middleware.ReadConsistency(handler)
+
+	// Cache-Control policies apply to successful responses of their routes
+	handler = cacheControl(handler)
+
+	// Rate limiting runs after authentication so users are limited by account
+	if limiter != nil {
+		handler = middleware.RateLimit(limiter, router, log.With("component", "rate_limit"))(handler)
+	}
+
+	// Authentication middleware attaches the caller's principal
+	handler = middleware.Authenticate(tokens)(handler)
+
+	// Recovery middleware turns panics into 500 responses and reports them
+	handler = middleware.RecoveryMiddleware(log.With("component", "recovery"), panicReporter)(handler)
+
+	// Compression middleware encodes bodies below logging so the logged status is unaffected
+	if cfg.Compression.Enabled {
+		handler = middleware.Compression(cfg.Compression)(handler)
+	}
+
+	// Body size limit rejects oversized payloads before handlers read them
+	if cfg.Security.MaxBodyBytes > 0 {
+		handler = middleware.MaxBodySize(cfg.Security.MaxBodyBytes)(handler)
+	}
+
 	// Logging middleware
-	handler = middleware.LoggingMiddleware(handler)
+	handler = middleware.LoggingMiddleware(log.With("component", "http"))(handler)
 	
+	// Metrics middleware labels requests by route template
+	handler = middleware.Metrics(appMetrics, router)(handler)
+
+	// Request ID middleware correlates logs and error responses
+	handler = middleware.RequestID()(handler)
+
+	// Tracing middleware continues the caller's trace and names spans by route template
+	handler = middleware.Tracing(router)(handler)
+
 	// CORS middleware
-	handler = middleware.DefaultCORS()(handler)
+	handler = corsMiddleware(handler)
+
+	// Security headers apply to every response, including CORS preflights
+	handler = securityHeaders(handler)
 
 	return handler
 }
 
-// healthCheck handles health check requests
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","timestamp":"%s"}`, time.Now().Format(time.RFC3339))
+// reloadRuntimeConfig returns the function applying reloaded settings to the running server.
+// Every new component is built before any is installed, so a failure changes nothing.
+func reloadRuntimeConfig(logLevel *slog.LevelVar, cors, cacheControl *middleware.Reloadable, router *mux.Router, limiter *ratelimit.Limiter, featureFlags *features.Flags) func(*config.Config) error {
+	return func(cfg *config.Config) error {
+		level, err := logger.ParseLevel(cfg.Logging.Level)
+		if err != nil {
+			return err
+		}
+
+		corsMiddleware, err := middleware.NewCORS(cfg.CORS)
+		if err != nil {
+			return err
+		}
+
+		cacheControlMiddleware, err := middleware.NewCacheControl(cfg.CacheControl, router)
+		if err != nil {
+			return err
+		}
+
+		if limiter != nil {
+			if err := limiter.Update(cfg.RateLimit); err != nil {
+				return err
+			}
+		}
+
+		logLevel.Set(level)
+		cors.Swap(corsMiddleware)
+		cacheControl.Swap(cacheControlMiddleware)
+		featureFlags.Set(cfg.Features.Enabled)
+		return nil
+	}
+}
+
+// newRateLimiter creates the rate limiter with the configured bucket store
+func newRateLimiter(cfg config.RateLimitConfig, db *database.DB, log logger.Logger) (*ratelimit.Limiter, error) {
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		store = ratelimit.NewPostgresStore(database.NewRateLimitRepository(db))
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+
+	return ratelimit.NewLimiter(store, cfg, log)
 }
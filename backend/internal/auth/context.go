@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"context"
+)
+
+type contextKey struct{}
+
+// Principal identifies the authenticated caller of a request
+type Principal struct {
+	UserID    int
+	OrgID     int
+	Role      string
+	TokenType string
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
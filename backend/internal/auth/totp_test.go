@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	key, err := GenerateTOTPKey("goapi", "user@example.com")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	// Keep the cases within one period so the current step does not move under them
+	period := int64(totpOpts.Period)
+	if time.Now().Unix()%period >= period-2 {
+		time.Sleep(3 * time.Second)
+	}
+	current := time.Now().Unix() / period
+
+	codeAt := func(step int64) string {
+		code, err := totp.GenerateCodeCustom(key.Secret(), time.Unix(step*period, 0).UTC(), totpOpts)
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(current), current, true},
+		{"previous step", codeAt(current - 1), current - 1, true},
+		{"next step", codeAt(current + 1), current + 1, true},
+		{"surrounding whitespace", " " + codeAt(current) + "\n", current, true},
+		{"two steps old", codeAt(current - 2), 0, false},
+		{"two steps ahead", codeAt(current + 2), 0, false},
+		{"not a code", "abcdef", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(key.Secret(), tt.code)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("ValidateTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
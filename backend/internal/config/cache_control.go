@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CacheControlConfig sets the Cache-Control header of successful responses. Policies list
+// their directives separated by "|", e.g. "private|max-age=60", as commas separate entries.
+type CacheControlConfig struct {
+	// Default applies to GET routes without a policy of their own; empty leaves the header unset
+	Default string `json:"default" yaml:"default" env:"CACHE_CONTROL_DEFAULT" reload:"true"`
+	// Routes set the policy of a route, written as "<METHOD> <path template>=<policy>",
+	// e.g. "GET /api/users/{id}=private|no-cache"
+	Routes []string `json:"routes" yaml:"routes" env:"CACHE_CONTROL_ROUTES" reload:"true"`
+}
+
+// DefaultPolicy returns the default policy as a Cache-Control value
+func (c CacheControlConfig) DefaultPolicy() (string, error) {
+	return parseCacheControl(c.Default)
+}
+
+// RoutePolicies returns the per-route policies as Cache-Control values keyed by
+// "<METHOD> <path template>"
+func (c CacheControlConfig) RoutePolicies() (map[string]string, error) {
+	policies := make(map[string]string, len(c.Routes))
+	for _, entry := range c.Routes {
+		route, policy, ok := strings.Cut(entry, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		path = strings.TrimSpace(path)
+		if !ok || !hasPath || !isToken(method) || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route policy %q: expected \"<METHOD> <path>=<directive>|<directive>\"", entry)
+		}
+
+		value, err := parseCacheControl(policy)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		policies[strings.ToUpper(method)+" "+path] = value
+	}
+	return policies, nil
+}
+
+// Validate checks the Cache-Control policies, reporting every problem found
+func (c CacheControlConfig) Validate() error {
+	var errs []error
+
+	if _, err := c.DefaultPolicy(); err != nil {
+		errs = append(errs, fmt.Errorf("CACHE_CONTROL_DEFAULT: %w", err))
+	}
+	if _, err := c.RoutePolicies(); err != nil {
+		errs = append(errs, fmt.Errorf("CACHE_CONTROL_ROUTES: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseCacheControl joins "|"-separated directives into a Cache-Control value. Each directive is
+// a token, optionally followed by "=" and a token or quoted string.
+func parseCacheControl(policy string) (string, error) {
+	var directives []string
+	for _, directive := range strings.Split(policy, "|") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(directive, "=")
+		valid := isToken(name)
+		if hasValue {
+			quoted := len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) &&
+				!strings.ContainsAny(value[1:len(value)-1], "\"\r\n")
+			valid = valid && (isToken(value) || quoted)
+		}
+		if !valid {
+			return "", fmt.Errorf("invalid directive %q", directive)
+		}
+		directives = append(directives, strings.ToLower(name)+strings.TrimPrefix(directive, name))
+	}
+	return strings.Join(directives, ", "), nil
+}
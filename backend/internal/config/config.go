@@ -1,84 +1,391 @@
 package config
 
 import (
-	"os"
+	"fmt"
+	"net"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
-// Config holds all configuration for our application
+// Config holds all configuration for our application. Each setting has a key used in config
+// files and flags (the json/yaml tags joined by a dot, e.g. "server.port") and an environment
+// variable (the env tag). Settings tagged secret are redacted when the configuration is printed,
+// and settings tagged reload can change when the configuration is reloaded while running.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Logging  LoggingConfig
+	Server       ServerConfig       `json:"server" yaml:"server"`
+	Database     DatabaseConfig     `json:"database" yaml:"database"`
+	Logging      LoggingConfig      `json:"logging" yaml:"logging"`
+	Auth         AuthConfig         `json:"auth" yaml:"auth"`
+	Mail         MailConfig         `json:"mail" yaml:"mail"`
+	Lockout      LockoutConfig      `json:"lockout" yaml:"lockout"`
+	Tracing      TracingConfig      `json:"tracing" yaml:"tracing"`
+	RateLimit    RateLimitConfig    `json:"rate_limit" yaml:"rate_limit"`
+	CORS         CORSConfig         `json:"cors" yaml:"cors"`
+	Health       HealthConfig       `json:"health" yaml:"health"`
+	Compression  CompressionConfig  `json:"compression" yaml:"compression"`
+	Security     SecurityConfig     `json:"security" yaml:"security"`
+	Panic        PanicConfig        `json:"panic" yaml:"panic"`
+	Secrets      SecretsConfig      `json:"secrets" yaml:"secrets"`
+	Features     FeaturesConfig     `json:"features" yaml:"features"`
+	UserCache    UserCacheConfig    `json:"user_cache" yaml:"user_cache"`
+	CacheControl CacheControlConfig `json:"cache_control" yaml:"cache_control"`
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port         string
-	Host         string
-	ReadTimeout  int
-	WriteTimeout int
+	Port         string `json:"port" yaml:"port" env:"SERVER_PORT"`
+	Host         string `json:"host" yaml:"host" env:"SERVER_HOST"`
+	ReadTimeout  int    `json:"read_timeout" yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout int    `json:"write_timeout" yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	Environment  string `json:"environment" yaml:"environment" env:"APP_ENV"`
+	// ShutdownDelay is how long, in seconds, readiness fails before the server stops accepting requests
+	ShutdownDelay int `json:"shutdown_delay" yaml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
 }
 
-// DatabaseConfig holds database-related configuration
+// DatabaseConfig holds database-related configuration. It can also be set as a whole from a
+// connection URL with the database.url key or DATABASE_URL.
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	// Driver is postgres or sqlite; SQLite stores everything in the SQLitePath file
+	Driver     string `json:"driver" yaml:"driver" env:"DB_DRIVER"`
+	SQLitePath string `json:"sqlite_path" yaml:"sqlite_path" env:"DB_SQLITE_PATH"`
+
+	Host     string `json:"host" yaml:"host" env:"DB_HOST"`
+	Port     string `json:"port" yaml:"port" env:"DB_PORT"`
+	User     string `json:"user" yaml:"user" env:"DB_USER"`
+	Password string `json:"password" yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	DBName   string `json:"name" yaml:"name" env:"DB_NAME"`
+	SSLMode  string `json:"sslmode" yaml:"sslmode" env:"DB_SSLMODE"`
+
+	// Connection pool; 0 means no limit for the sizes and durations (in seconds)
+	MaxOpenConns    int `json:"max_open_conns" yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int `json:"max_idle_conns" yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxIdleTime int `json:"conn_max_idle_time" yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	ConnMaxLifetime int `json:"conn_max_lifetime" yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+
+	// Session options; ConnectTimeout is in seconds and StatementTimeoutMs 0 disables the timeout
+	ConnectTimeout     int    `json:"connect_timeout" yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	StatementTimeoutMs int    `json:"statement_timeout_ms" yaml:"statement_timeout_ms" env:"DB_STATEMENT_TIMEOUT_MS"`
+	ApplicationName    string `json:"application_name" yaml:"application_name" env:"DB_APPLICATION_NAME"`
+	SearchPath         string `json:"search_path" yaml:"search_path" env:"DB_SEARCH_PATH"`
+
+	// Startup retry: attempts to reach the database, waiting with exponential backoff in between
+	ConnectAttempts  int `json:"connect_attempts" yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	RetryBaseDelayMs int `json:"retry_base_delay_ms" yaml:"retry_base_delay_ms" env:"DB_RETRY_BASE_DELAY_MS"`
+	RetryMaxDelayMs  int `json:"retry_max_delay_ms" yaml:"retry_max_delay_ms" env:"DB_RETRY_MAX_DELAY_MS"`
+
+	// Read replicas, each "host[:port]" sharing the primary's other settings or a postgres:// URL.
+	// Reads within ReadYourWritesWindow seconds of a write in the same session use the primary.
+	Replicas             []string `json:"replicas" yaml:"replicas" env:"DB_REPLICAS" secret:"true"`
+	ReplicaCheckInterval int      `json:"replica_check_interval" yaml:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL"`
+	ReadYourWritesWindow int      `json:"read_your_writes_window" yaml:"read_your_writes_window" env:"DB_READ_YOUR_WRITES_WINDOW"`
+}
+
+// ReplicaConfigs returns the connection settings of each read replica
+func (d DatabaseConfig) ReplicaConfigs() ([]DatabaseConfig, error) {
+	replicas := make([]DatabaseConfig, 0, len(d.Replicas))
+	for _, entry := range d.Replicas {
+		replica := d
+		replica.Replicas = nil
+
+		if strings.Contains(entry, "://") {
+			if err := replica.setURL(entry); err != nil {
+				return nil, fmt.Errorf("replica %q: %w", redactURL(entry), err)
+			}
+		} else {
+			host, port, err := net.SplitHostPort(entry)
+			if err != nil {
+				host, port = entry, d.Port
+			}
+			replica.Host, replica.Port = host, port
+		}
+
+		if replica.Host == "" || !isPort(replica.Port) {
+			return nil, fmt.Errorf("invalid replica %q: expected host[:port] or a postgres:// URL", redactURL(entry))
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+// redactURL hides the password of a URL for error messages, including URLs that do not parse
+func redactURL(entry string) string {
+	u, err := url.Parse(entry)
+	if err == nil {
+		return u.Redacted()
+	}
+
+	scheme := strings.Index(entry, "://")
+	if at := strings.LastIndex(entry, "@"); scheme >= 0 && at > scheme {
+		return entry[:scheme+3] + "xxxxx" + entry[at:]
+	}
+	return entry
 }
 
 // LoggingConfig holds logging-related configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level  string `json:"level" yaml:"level" env:"LOG_LEVEL" reload:"true"`
+	Format string `json:"format" yaml:"format" env:"LOG_FORMAT"`
 }
 
-// LoadConfig loads configuration from environment variables with defaults
-func LoadConfig() *Config {
+// AuthConfig holds authentication-related configuration
+type AuthConfig struct {
+	TokenSecret          string   `json:"token_secret" yaml:"token_secret" env:"AUTH_TOKEN_SECRET" secret:"true"`
+	TokenTTL             int      `json:"token_ttl" yaml:"token_ttl" env:"AUTH_TOKEN_TTL"`
+	MFAIssuer            string   `json:"mfa_issuer" yaml:"mfa_issuer" env:"MFA_ISSUER"`
+	MFARequiredRoles     []string `json:"mfa_required_roles" yaml:"mfa_required_roles" env:"MFA_REQUIRED_ROLES"`
+	VerificationTokenTTL int      `json:"verification_token_ttl" yaml:"verification_token_ttl" env:"AUTH_VERIFICATION_TOKEN_TTL"`
+	PasswordResetTTL     int      `json:"password_reset_ttl" yaml:"password_reset_ttl" env:"AUTH_PASSWORD_RESET_TTL"`
+	InvitationTTL        int      `json:"invitation_ttl" yaml:"invitation_ttl" env:"AUTH_INVITATION_TTL"`
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver       string `json:"driver" yaml:"driver" env:"MAIL_DRIVER"`
+	From         string `json:"from" yaml:"from" env:"MAIL_FROM"`
+	BaseURL      string `json:"base_url" yaml:"base_url" env:"MAIL_BASE_URL"`
+	SMTPHost     string `json:"smtp_host" yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `json:"smtp_port" yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `json:"smtp_username" yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `json:"smtp_password" yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	FileDir      string `json:"file_dir" yaml:"file_dir" env:"MAIL_FILE_DIR"`
+}
+
+// LockoutConfig holds brute-force protection settings for login
+type LockoutConfig struct {
+	MaxAccountFailures int `json:"max_account_failures" yaml:"max_account_failures" env:"LOCKOUT_MAX_ACCOUNT_FAILURES"`
+	MaxIPFailures      int `json:"max_ip_failures" yaml:"max_ip_failures" env:"LOCKOUT_MAX_IP_FAILURES"`
+	FailureWindow      int `json:"failure_window" yaml:"failure_window" env:"LOCKOUT_FAILURE_WINDOW"`
+	Duration           int `json:"duration" yaml:"duration" env:"LOCKOUT_DURATION"`
+	BaseDelayMs        int `json:"base_delay_ms" yaml:"base_delay_ms" env:"LOCKOUT_BASE_DELAY_MS"`
+	MaxDelayMs         int `json:"max_delay_ms" yaml:"max_delay_ms" env:"LOCKOUT_MAX_DELAY_MS"`
+}
+
+// TracingConfig holds OpenTelemetry tracing settings
+type TracingConfig struct {
+	Exporter    string  `json:"exporter" yaml:"exporter" env:"TRACING_EXPORTER"`
+	ServiceName string  `json:"service_name" yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// HealthConfig holds readiness check settings
+type HealthConfig struct {
+	CheckTimeout        int     `json:"check_timeout" yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	CacheTTL            int     `json:"cache_ttl" yaml:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+	SaturationThreshold float64 `json:"saturation_threshold" yaml:"saturation_threshold" env:"HEALTH_POOL_SATURATION_THRESHOLD"`
+}
+
+// PanicConfig selects where recovered panics are reported besides the log
+type PanicConfig struct {
+	Reporter string `json:"reporter" yaml:"reporter" env:"PANIC_REPORTER"`
+	FilePath string `json:"file_path" yaml:"file_path" env:"PANIC_REPORT_FILE"`
+}
+
+// CompressionConfig holds response compression settings. Level uses the gzip scale
+// (1 fastest to 9 smallest, -1 for the default) and ContentTypes accepts "type/*" entries.
+type CompressionConfig struct {
+	Enabled      bool     `json:"enabled" yaml:"enabled" env:"COMPRESSION_ENABLED"`
+	MinSize      int      `json:"min_size" yaml:"min_size" env:"COMPRESSION_MIN_SIZE"`
+	Level        int      `json:"level" yaml:"level" env:"COMPRESSION_LEVEL"`
+	ContentTypes []string `json:"content_types" yaml:"content_types" env:"COMPRESSION_TYPES"`
+}
+
+// RateLimitConfig holds request rate limiting settings. Policies are written as
+// "<limit>/<window>" (e.g. "10/1m") or "unlimited"; routes as "<METHOD> <path template>=<policy>".
+type RateLimitConfig struct {
+	Enabled bool     `json:"enabled" yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Store   string   `json:"store" yaml:"store" env:"RATE_LIMIT_STORE"`
+	Default string   `json:"default" yaml:"default" env:"RATE_LIMIT_DEFAULT" reload:"true"`
+	Routes  []string `json:"routes" yaml:"routes" env:"RATE_LIMIT_ROUTES" reload:"true"`
+	APIKeys []string `json:"api_keys" yaml:"api_keys" env:"RATE_LIMIT_API_KEYS" secret:"true" reload:"true"`
+}
+
+// FeaturesConfig lists the enabled feature flags
+type FeaturesConfig struct {
+	Enabled []string `json:"enabled" yaml:"enabled" env:"FEATURE_FLAGS" reload:"true"`
+}
+
+// UserCacheConfig configures the in-process cache of user lookups. TTL is in seconds; with
+// Notify, instances sharing a PostgreSQL database invalidate each other's entries.
+type UserCacheConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled" env:"USER_CACHE_ENABLED"`
+	Size    int  `json:"size" yaml:"size" env:"USER_CACHE_SIZE"`
+	TTL     int  `json:"ttl" yaml:"ttl" env:"USER_CACHE_TTL"`
+	Notify  bool `json:"notify" yaml:"notify" env:"USER_CACHE_NOTIFY"`
+}
+
+// Defaults returns the configuration used when no file, environment variable or flag sets a value
+func Defaults() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			Host:         getEnv("SERVER_HOST", "0.0.0.0"),
-			ReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 30),
-			WriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 30),
+			Port:          "8080",
+			Host:          "0.0.0.0",
+			ReadTimeout:   30,
+			WriteTimeout:  30,
+			Environment:   "development",
+			ShutdownDelay: 5,
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "pass"),
-			DBName:   getEnv("DB_NAME", "postgres"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Driver:     "postgres",
+			SQLitePath: "goapi.db",
+
+			Host:     "localhost",
+			Port:     "5432",
+			User:     "postgres",
+			Password: "pass",
+			DBName:   "postgres",
+			SSLMode:  "disable",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 300,
+
+			ConnectTimeout:  10,
+			ApplicationName: "goapi",
+
+			ConnectAttempts:  10,
+			RetryBaseDelayMs: 500,
+			RetryMaxDelayMs:  10000,
+
+			ReplicaCheckInterval: 5,
+			ReadYourWritesWindow: 5,
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:  "info",
+			Format: "json",
 		},
-	}
-}
+		Auth: AuthConfig{
+			TokenSecret:      "change-me",
+			TokenTTL:         60,
+			MFAIssuer:        "GoAPI",
+			MFARequiredRoles: []string{"admin", "superadmin"},
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+			VerificationTokenTTL: 24 * 60,
+			PasswordResetTTL:     30,
+			InvitationTTL:        7 * 24 * 60,
+		},
+		Mail: MailConfig{
+			Driver:   "log",
+			From:     "no-reply@localhost",
+			BaseURL:  "http://localhost:5173",
+			SMTPHost: "localhost",
+			SMTPPort: "587",
+			FileDir:  "mail",
+		},
+		Lockout: LockoutConfig{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			FailureWindow:      15,
+			Duration:           15,
+			BaseDelayMs:        250,
+			MaxDelayMs:         8000,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "goapi",
+			SampleRatio: 1.0,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Default: "300/1m",
+			Routes: []string{
+				"POST /api/users=10/1m",
+				"POST /api/auth/login=20/1m",
+				"POST /api/auth/password/forgot=5/1m",
+				"GET /health=unlimited",
+				"GET /livez=unlimited",
+				"GET /readyz=unlimited",
+				"GET /metrics=unlimited",
+			},
+		},
+		Health: HealthConfig{
+			CheckTimeout:        2,
+			CacheTTL:            2,
+			SaturationThreshold: 0.9,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"*"},
+			ExposedHeaders: []string{
+				"X-Request-ID", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+				"ETag", "Last-Modified",
+			},
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:            31536000,
+			HSTSIncludeSubdomains: true,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			MaxBodyBytes:          1 << 20,
+		},
+		Panic: PanicConfig{
+			Reporter: "none",
+			FilePath: "panics.jsonl",
+		},
+		Secrets: SecretsConfig{
+			Provider: "env",
+			Dir:      "/run/secrets",
+			File:     "secrets.json",
+		},
+		Compression: CompressionConfig{
+			Enabled: true,
+			MinSize: 1024,
+			Level:   -1,
+			ContentTypes: []string{
+				"application/json", "application/x-ndjson", "application/problem+json",
+				"application/javascript", "application/xml", "image/svg+xml", "text/*",
+			},
+		},
+		UserCache: UserCacheConfig{
+			Enabled: true,
+			Size:    10000,
+			TTL:     30,
+		},
+		CacheControl: CacheControlConfig{
+			Routes: []string{
+				"GET /api/users=private|no-cache",
+				"GET /api/users/{id}=private|no-cache",
+			},
+		},
 	}
-	return defaultValue
 }
 
-// getEnvAsInt gets an environment variable as integer or returns a default value
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
+// IsDevelopment reports whether the application runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.Server.Environment == "development"
 }
 
 // GetDatabaseURL returns the complete database connection string
 func (c *Config) GetDatabaseURL() string {
-	return "postgres://" + c.Database.User + ":" + c.Database.Password + "@" + c.Database.Host + ":" + c.Database.Port + "/" + c.Database.DBName + "?sslmode=" + c.Database.SSLMode
+	return c.Database.URL()
+}
+
+// URL returns the connection URL with the credentials and database name escaped. Session
+// options are passed as connection parameters.
+func (d DatabaseConfig) URL() string {
+	query := url.Values{"sslmode": {d.SSLMode}}
+	if d.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(d.ConnectTimeout))
+	}
+	if d.StatementTimeoutMs > 0 {
+		query.Set("statement_timeout", strconv.Itoa(d.StatementTimeoutMs))
+	}
+	if d.ApplicationName != "" {
+		query.Set("application_name", d.ApplicationName)
+	}
+	if d.SearchPath != "" {
+		query.Set("search_path", d.SearchPath)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     net.JoinHostPort(d.Host, d.Port),
+		Path:     "/" + d.DBName,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
 }
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CORSConfig holds cross-origin resource sharing settings
+type CORSConfig struct {
+	// AllowedOrigins lists exact origins, "*" for any origin, or wildcard subdomains
+	// such as "https://*.example.com"
+	AllowedOrigins   []string `json:"allowed_origins" yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" reload:"true"`
+	AllowedMethods   []string `json:"allowed_methods" yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS" reload:"true"`
+	AllowedHeaders   []string `json:"allowed_headers" yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS" reload:"true"`
+	ExposedHeaders   []string `json:"exposed_headers" yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS" reload:"true"`
+	AllowCredentials bool     `json:"allow_credentials" yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" reload:"true"`
+	// MaxAge is how long, in seconds, browsers may cache preflight responses; 0 leaves it unset
+	MaxAge int `json:"max_age" yaml:"max_age" env:"CORS_MAX_AGE" reload:"true"`
+	// Routes override the allowed origins for a path prefix, written as
+	// "<path prefix>=<origin>|<origin>", e.g. "/api/public=*"
+	Routes []string `json:"routes" yaml:"routes" env:"CORS_ROUTES" reload:"true"`
+}
+
+// CORSRoute overrides the allowed origins for requests whose path starts with Prefix
+type CORSRoute struct {
+	Prefix         string
+	AllowedOrigins []string
+}
+
+// RouteOverrides parses the per-route origin overrides
+func (c CORSConfig) RouteOverrides() ([]CORSRoute, error) {
+	routes := make([]CORSRoute, 0, len(c.Routes))
+	for _, entry := range c.Routes {
+		prefix, origins, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid route override %q: expected \"<path prefix>=<origin>|<origin>\"", entry)
+		}
+
+		var allowed []string
+		for _, origin := range strings.Split(origins, "|") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				allowed = append(allowed, origin)
+			}
+		}
+		routes = append(routes, CORSRoute{Prefix: prefix, AllowedOrigins: allowed})
+	}
+	return routes, nil
+}
+
+// Validate checks the CORS settings, reporting every problem found
+func (c CORSConfig) Validate() error {
+	var errs []error
+
+	errs = append(errs, validateOrigins("CORS_ALLOWED_ORIGINS", c.AllowedOrigins, c.AllowCredentials)...)
+
+	if len(c.AllowedMethods) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOWED_METHODS: at least one method is required"))
+	}
+	for _, method := range c.AllowedMethods {
+		if !isToken(method) || method != strings.ToUpper(method) {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_METHODS: invalid method %q", method))
+		}
+	}
+
+	for _, header := range c.AllowedHeaders {
+		if header != "*" && !isToken(header) {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_HEADERS: invalid header %q", header))
+		}
+	}
+
+	for _, header := range c.ExposedHeaders {
+		if !isToken(header) {
+			errs = append(errs, fmt.Errorf("CORS_EXPOSED_HEADERS: invalid header %q", header))
+		}
+	}
+
+	if c.MaxAge < 0 {
+		errs = append(errs, errors.New("CORS_MAX_AGE: must not be negative"))
+	}
+
+	routes, err := c.RouteOverrides()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("CORS_ROUTES: %w", err))
+	}
+	for _, route := range routes {
+		errs = append(errs, validateOrigins("CORS_ROUTES "+route.Prefix, route.AllowedOrigins, c.AllowCredentials)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateOrigins checks that each origin is "*" or a scheme and host, optionally with a
+// single leading wildcard subdomain label. Credentials cannot be combined with "*".
+func validateOrigins(name string, origins []string, credentials bool) []error {
+	var errs []error
+
+	if len(origins) == 0 {
+		errs = append(errs, fmt.Errorf("%s: at least one origin is required", name))
+	}
+
+	for _, origin := range origins {
+		if origin == "*" {
+			if credentials {
+				errs = append(errs, fmt.Errorf("%s: \"*\" cannot be used with CORS_ALLOW_CREDENTIALS", name))
+			}
+			continue
+		}
+
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil ||
+			strings.Contains(u.Host, "*") {
+			errs = append(errs, fmt.Errorf("%s: invalid origin %q: expected scheme://host[:port] or scheme://*.domain", name, origin))
+		}
+	}
+
+	return errs
+}
+
+// isToken reports whether s is a valid HTTP token (RFC 9110), as used for methods and header names
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
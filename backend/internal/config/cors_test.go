@@ -0,0 +1,52 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCORSConfigValidateOrigins(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		credentials bool
+		routes      []string
+		// wantErr is a substring of the error, or empty when the configuration is valid
+		wantErr string
+	}{
+		{name: "exact origins", origins: []string{"https://app.example.com", "http://localhost:3000"}},
+		{name: "wildcard subdomain", origins: []string{"https://*.example.com"}},
+		{name: "any origin", origins: []string{"*"}},
+		{name: "trailing slash", origins: []string{"https://app.example.com/"}},
+		{name: "no origins", wantErr: "at least one origin is required"},
+		{name: "any origin with credentials", origins: []string{"*"}, credentials: true, wantErr: "cannot be used with CORS_ALLOW_CREDENTIALS"},
+		{name: "missing scheme", origins: []string{"app.example.com"}, wantErr: "invalid origin"},
+		{name: "unsupported scheme", origins: []string{"ftp://app.example.com"}, wantErr: "invalid origin"},
+		{name: "path", origins: []string{"https://app.example.com/api"}, wantErr: "invalid origin"},
+		{name: "query", origins: []string{"https://app.example.com?x=1"}, wantErr: "invalid origin"},
+		{name: "user info", origins: []string{"https://user@app.example.com"}, wantErr: "invalid origin"},
+		{name: "inner wildcard", origins: []string{"https://app.*.example.com"}, wantErr: "invalid origin"},
+		{name: "route override", origins: []string{"https://app.example.com"}, routes: []string{"/api/public=*"}},
+		{name: "route override without prefix", origins: []string{"https://app.example.com"}, routes: []string{"api=*"}, wantErr: "invalid route override"},
+		{name: "route override origin", origins: []string{"https://app.example.com"}, routes: []string{"/api/public=example.com"}, wantErr: "CORS_ROUTES /api/public: invalid origin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CORSConfig{
+				AllowedOrigins:   tt.origins,
+				AllowedMethods:   []string{"GET", "POST"},
+				AllowCredentials: tt.credentials,
+				Routes:           tt.routes,
+			}
+
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change describes a setting that differs between two configurations. Secret values are redacted.
+type Change struct {
+	Key        string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+// String formats the change as "<key>: <old> -> <new>"
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// Diff lists the settings that differ from old to new, in declaration order
+func Diff(old, new *Config) []Change {
+	oldValue := reflect.ValueOf(old).Elem()
+	newValue := reflect.ValueOf(new).Elem()
+
+	var changes []Change
+	for _, s := range settings() {
+		before := formatSetting(oldValue.FieldByIndex(s.index))
+		after := formatSetting(newValue.FieldByIndex(s.index))
+		if before == after {
+			continue
+		}
+
+		if s.secret {
+			before, after = redacted, redacted
+		}
+		changes = append(changes, Change{Key: s.key, Old: before, New: after, Reloadable: s.reload})
+	}
+	return changes
+}
+
+// formatSetting renders a setting value, lists as comma-separated items
+func formatSetting(v reflect.Value) string {
+	if list, ok := v.Interface().([]string); ok {
+		return "[" + strings.Join(list, ",") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,442 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// databaseURLKey sets every database setting from a connection URL
+const (
+	databaseURLKey = "database.url"
+	databaseURLEnv = "DATABASE_URL"
+)
+
+// Options selects the sources layered over the defaults. Later sources take precedence:
+// defaults, then the config file, then environment variables, then command-line flags.
+// Secrets from a file or vault provider override every source except flags.
+type Options struct {
+	// File is a YAML, TOML or JSON config file; the extension selects the format
+	File string
+	// EnvPrefix is prepended to every environment variable name, e.g. "GOAPI_" reads GOAPI_SERVER_PORT
+	EnvPrefix string
+	// Flags holds values set on the command line by setting key, e.g. "server.port"
+	Flags map[string]string
+}
+
+// setting describes one configurable field
+type setting struct {
+	key    string
+	env    string
+	secret bool
+	reload bool
+	index  []int
+}
+
+var (
+	settingsOnce sync.Once
+	allSettings  []setting
+)
+
+// settings lists every configurable field in declaration order
+func settings() []setting {
+	settingsOnce.Do(func() {
+		configType := reflect.TypeOf(Config{})
+		for i := 0; i < configType.NumField(); i++ {
+			section := configType.Field(i)
+			for j := 0; j < section.Type.NumField(); j++ {
+				field := section.Type.Field(j)
+				allSettings = append(allSettings, setting{
+					key:    section.Tag.Get("json") + "." + field.Tag.Get("json"),
+					env:    field.Tag.Get("env"),
+					secret: field.Tag.Get("secret") == "true",
+					reload: field.Tag.Get("reload") == "true",
+					index:  []int{i, j},
+				})
+			}
+		}
+	})
+	return allSettings
+}
+
+// NewFlagSet creates a flag set that records into opts: -config, -env-prefix and one flag per
+// setting named by its key, e.g. -server.port. CONFIG_FILE and CONFIG_ENV_PREFIX provide the
+// defaults for the first two.
+func NewFlagSet(name string, opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if opts.Flags == nil {
+		opts.Flags = make(map[string]string)
+	}
+
+	fs.StringVar(&opts.File, "config", os.Getenv("CONFIG_FILE"), "path to a YAML, TOML or JSON config file")
+	fs.StringVar(&opts.EnvPrefix, "env-prefix", os.Getenv("CONFIG_ENV_PREFIX"), "prefix for configuration environment variables")
+
+	fs.Func(databaseURLKey, "database connection URL (env "+databaseURLEnv+")", func(value string) error {
+		opts.Flags[databaseURLKey] = value
+		return nil
+	})
+
+	configType := reflect.TypeOf(Config{})
+	for _, s := range settings() {
+		key := s.key
+		usage := fmt.Sprintf("%s (env %s)", key, s.env)
+		record := func(value string) error {
+			opts.Flags[key] = value
+			return nil
+		}
+
+		if configType.FieldByIndex(s.index).Type.Kind() == reflect.Bool {
+			fs.BoolFunc(key, usage, record)
+		} else {
+			fs.Func(key, usage, record)
+		}
+	}
+
+	return fs
+}
+
+// Load reads the configuration from every source and validates it, reporting all problems at once
+func Load(opts Options) (*Config, error) {
+	cfg, err := Read(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read layers the config file, environment variables and flags over the defaults without
+// validating the result. Values that cannot be parsed are reported together.
+func Read(opts Options) (*Config, error) {
+	cfg := Defaults()
+	var errs []error
+
+	if opts.File != "" {
+		values, err := readFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, cfg.apply(values, func(key string) string { return opts.File + ": " + key })...)
+	}
+
+	envs, envErrs := envValues(opts.EnvPrefix)
+	errs = append(errs, envErrs...)
+	errs = append(errs, cfg.apply(envs, func(key string) string {
+		if s, ok := settingByKey(key); ok {
+			return opts.EnvPrefix + s.env
+		}
+		return opts.EnvPrefix + databaseURLEnv
+	})...)
+
+	flagValues := make(map[string]interface{}, len(opts.Flags))
+	for key, value := range opts.Flags {
+		flagValues[key] = value
+	}
+	errs = append(errs, cfg.apply(flagValues, func(key string) string { return "-" + key })...)
+
+	// Secrets are resolved last so the provider can itself be chosen by any source
+	errs = append(errs, cfg.applySecrets(opts)...)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// settingByKey finds the setting with key
+func settingByKey(key string) (setting, bool) {
+	for _, s := range settings() {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// envValues collects the non-empty environment variables for every setting. Secrets can instead
+// be read from the file named by the variable with a _FILE suffix, e.g. DB_PASSWORD_FILE.
+func envValues(prefix string) (map[string]interface{}, []error) {
+	values := make(map[string]interface{})
+	var errs []error
+
+	lookup := func(key, env string, secret bool) {
+		value := os.Getenv(prefix + env)
+		if secret {
+			if path := os.Getenv(prefix + env + "_FILE"); path != "" {
+				if value != "" {
+					errs = append(errs, fmt.Errorf("%s: set only one of %s and %s_FILE", prefix+env, prefix+env, prefix+env))
+					return
+				}
+
+				var err error
+				if value, err = readSecretFile(path); err != nil {
+					errs = append(errs, fmt.Errorf("%s_FILE: %w", prefix+env, err))
+					return
+				}
+			}
+		}
+
+		if value != "" {
+			values[key] = value
+		}
+	}
+
+	lookup(databaseURLKey, databaseURLEnv, true)
+	for _, s := range settings() {
+		lookup(s.key, s.env, s.secret)
+	}
+	return values, errs
+}
+
+// applySecrets sets secret settings from the configured provider, skipping settings given as
+// flags. The env provider adds nothing since environment variables are already applied.
+func (c *Config) applySecrets(opts Options) []error {
+	if c.Secrets.Provider == "env" {
+		return nil
+	}
+
+	provider, err := NewSecretProvider(c.Secrets, opts.EnvPrefix)
+	if err != nil {
+		return []error{fmt.Errorf("SECRETS_PROVIDER: %w", err)}
+	}
+
+	var errs []error
+	target := reflect.ValueOf(c).Elem()
+	for _, s := range settings() {
+		if _, ok := opts.Flags[s.key]; !s.secret || ok {
+			continue
+		}
+
+		value, err := provider.Secret(context.Background(), secretName(s))
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err == nil {
+			err = setValue(target.FieldByIndex(s.index), value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s secret %s: %w", c.Secrets.Provider, secretName(s), err))
+		}
+	}
+	return errs
+}
+
+// apply sets the values of one source, keyed by setting key. A database URL is applied first so
+// individual database settings from the same source override its parts. name describes a key
+// in error messages.
+func (c *Config) apply(values map[string]interface{}, name func(key string) string) []error {
+	var errs []error
+
+	if raw, ok := values[databaseURLKey]; ok {
+		if err := c.Database.setURL(fmt.Sprint(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name(databaseURLKey), err))
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if key != databaseURLKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	target := reflect.ValueOf(c).Elem()
+	for _, key := range keys {
+		s, ok := settingByKey(key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting", name(key)))
+			continue
+		}
+
+		if err := setValue(target.FieldByIndex(s.index), values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name(key), err))
+		}
+	}
+
+	return errs
+}
+
+// setValue parses raw, a string or a list of strings, into the field
+func setValue(field reflect.Value, raw interface{}) error {
+	if field.Kind() == reflect.Slice {
+		var items []string
+		switch v := raw.(type) {
+		case []string:
+			items = v
+		default:
+			items = strings.Split(fmt.Sprint(v), ",")
+		}
+
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+		return nil
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("expected a single value, got a list")
+	}
+	value = strings.TrimSpace(value)
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || field.OverflowInt(n) {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// setURL sets the database settings from a postgres:// connection URL. Parts missing from the
+// URL keep their current values.
+func (d *DatabaseConfig) setURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("invalid database URL")
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("unsupported database URL scheme %q", u.Scheme)
+	}
+
+	if host := u.Hostname(); host != "" {
+		d.Host = host
+	}
+	if port := u.Port(); port != "" {
+		d.Port = port
+	}
+	if u.User != nil {
+		d.User = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			d.Password = password
+		}
+	}
+	if name := strings.TrimPrefix(u.Path, "/"); name != "" {
+		d.DBName = name
+	}
+
+	query := u.Query()
+	if sslmode := query.Get("sslmode"); sslmode != "" {
+		d.SSLMode = sslmode
+	}
+	if name := query.Get("application_name"); name != "" {
+		d.ApplicationName = name
+	}
+	if path := query.Get("search_path"); path != "" {
+		d.SearchPath = path
+	}
+	for param, target := range map[string]*int{
+		"connect_timeout":   &d.ConnectTimeout,
+		"statement_timeout": &d.StatementTimeoutMs,
+	} {
+		if value := query.Get(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s %q in database URL", param, value)
+			}
+			*target = n
+		}
+	}
+	return nil
+}
+
+// readFile parses a config file into setting values keyed by setting key
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q: expected .yaml, .yml, .toml or .json", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]interface{})
+	flatten("", doc, values)
+	return values, nil
+}
+
+// flatten converts nested sections into dotted keys with string or []string values
+func flatten(prefix string, doc map[string]interface{}, values map[string]interface{}) {
+	for name, value := range doc {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, values)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = formatScalar(item)
+			}
+			values[key] = items
+		default:
+			values[key] = formatScalar(v)
+		}
+	}
+}
+
+// formatScalar renders a decoded file value the way it would be written in an environment variable
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
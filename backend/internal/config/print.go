@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values in printed configuration
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the configuration with every non-empty secret replaced
+func (c *Config) Redacted() *Config {
+	copied := *c
+	target := reflect.ValueOf(&copied).Elem()
+
+	for _, s := range settings() {
+		if !s.secret {
+			continue
+		}
+
+		field := target.FieldByIndex(s.index)
+		switch field.Kind() {
+		case reflect.String:
+			if field.String() != "" {
+				field.SetString(redacted)
+			}
+		case reflect.Slice:
+			masked := make([]string, field.Len())
+			for i := range masked {
+				masked[i] = redacted
+			}
+			field.Set(reflect.ValueOf(masked))
+		}
+	}
+
+	return &copied
+}
+
+// Print writes the configuration with secrets redacted as yaml or json
+func (c *Config) Print(w io.Writer, format string) error {
+	safe := c.Redacted()
+
+	switch format {
+	case "yaml", "yml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(safe); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(safe)
+	default:
+		return fmt.Errorf("unknown output format %q: expected yaml or json", format)
+	}
+}
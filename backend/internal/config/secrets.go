@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSecretNotFound is returned by a SecretProvider that does not hold the requested secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretsConfig selects where secret settings are read from besides the usual sources
+type SecretsConfig struct {
+	// Provider is env, file (one file per secret in Dir) or vault (a vault-style JSON file)
+	Provider string `json:"provider" yaml:"provider" env:"SECRETS_PROVIDER"`
+	Dir      string `json:"dir" yaml:"dir" env:"SECRETS_DIR"`
+	File     string `json:"file" yaml:"file" env:"SECRETS_FILE"`
+}
+
+// SecretProvider looks up secrets by name. Names are the lower-cased environment variable of the
+// setting, e.g. "db_password" or "auth_token_secret".
+type SecretProvider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// NewSecretProvider creates the provider selected by configuration
+func NewSecretProvider(cfg SecretsConfig, envPrefix string) (SecretProvider, error) {
+	switch cfg.Provider {
+	case "env":
+		return NewEnvSecretProvider(envPrefix), nil
+	case "file":
+		return NewFileSecretProvider(cfg.Dir), nil
+	case "vault":
+		return NewVaultSecretProvider(cfg.File), nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+	}
+}
+
+// secretName is the provider name of a secret setting
+func secretName(s setting) string {
+	return strings.ToLower(s.env)
+}
+
+// EnvSecretProvider reads secrets from environment variables, e.g. "db_password" from DB_PASSWORD
+type EnvSecretProvider struct {
+	prefix string
+}
+
+// NewEnvSecretProvider creates a provider reading variables with the given prefix
+func NewEnvSecretProvider(prefix string) *EnvSecretProvider {
+	return &EnvSecretProvider{prefix: prefix}
+}
+
+// Secret returns the value of the secret's environment variable
+func (p *EnvSecretProvider) Secret(ctx context.Context, name string) (string, error) {
+	value := os.Getenv(p.prefix + strings.ToUpper(name))
+	if value == "" {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// FileSecretProvider reads each secret from a file named after it, as with Docker and Kubernetes
+// secrets mounted at /run/secrets. Files are read on every lookup so rotated secrets apply at once.
+type FileSecretProvider struct {
+	dir string
+}
+
+// NewFileSecretProvider creates a provider reading secret files from dir
+func NewFileSecretProvider(dir string) *FileSecretProvider {
+	return &FileSecretProvider{dir: dir}
+}
+
+// Secret returns the contents of the secret's file without a trailing newline
+func (p *FileSecretProvider) Secret(ctx context.Context, name string) (string, error) {
+	value, err := readSecretFile(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	return value, err
+}
+
+// VaultSecretProvider reads secrets from a JSON file in the shape written by Vault agent templates,
+// either {"data": {"data": {...}}} (KV version 2), {"data": {...}} or a flat object. The file is
+// reloaded when it changes, so rotated secrets apply without a restart.
+type VaultSecretProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	secrets map[string]string
+}
+
+// NewVaultSecretProvider creates a provider reading the JSON file at path
+func NewVaultSecretProvider(path string) *VaultSecretProvider {
+	return &VaultSecretProvider{path: path}
+}
+
+// Secret returns the named secret, reloading the file first if it changed
+func (p *VaultSecretProvider) Secret(ctx context.Context, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.reload(); err != nil {
+		return "", err
+	}
+
+	value, ok := p.secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// reload parses the file when its size or modification time changed since the last read
+func (p *VaultSecretProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if p.secrets != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse secrets file %s: %w", p.path, err)
+	}
+
+	// Unwrap the KV envelopes
+	for i := 0; i < 2; i++ {
+		inner, ok := doc["data"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		doc = inner
+	}
+
+	secrets := make(map[string]string, len(doc))
+	for name, value := range doc {
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = formatScalar(item)
+			}
+			secrets[name] = strings.Join(items, ",")
+			continue
+		}
+		secrets[name] = formatScalar(value)
+	}
+
+	p.secrets = secrets
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	return nil
+}
+
+// readSecretFile reads a secret from a file, dropping the trailing newline editors add
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
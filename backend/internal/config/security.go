@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SecurityConfig holds security response headers and request limits. Empty header values
+// leave the header unset.
+type SecurityConfig struct {
+	// HSTSMaxAge is the Strict-Transport-Security lifetime in seconds; 0 disables the header
+	HSTSMaxAge            int  `json:"hsts_max_age" yaml:"hsts_max_age" env:"SECURITY_HSTS_MAX_AGE"`
+	HSTSIncludeSubdomains bool `json:"hsts_include_subdomains" yaml:"hsts_include_subdomains" env:"SECURITY_HSTS_INCLUDE_SUBDOMAINS"`
+	HSTSPreload           bool `json:"hsts_preload" yaml:"hsts_preload" env:"SECURITY_HSTS_PRELOAD"`
+	// FrameOptions is DENY or SAMEORIGIN and is mirrored by the CSP frame-ancestors directive
+	FrameOptions          string `json:"frame_options" yaml:"frame_options" env:"SECURITY_FRAME_OPTIONS"`
+	ReferrerPolicy        string `json:"referrer_policy" yaml:"referrer_policy" env:"SECURITY_REFERRER_POLICY"`
+	ContentSecurityPolicy string `json:"content_security_policy" yaml:"content_security_policy" env:"SECURITY_CONTENT_SECURITY_POLICY"`
+	// MaxBodyBytes caps request bodies; 0 disables the limit
+	MaxBodyBytes int64 `json:"max_body_bytes" yaml:"max_body_bytes" env:"SECURITY_MAX_BODY_BYTES"`
+}
+
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+// Validate checks the security settings, reporting every problem found
+func (c SecurityConfig) Validate() error {
+	var errs []error
+
+	if c.HSTSMaxAge < 0 {
+		errs = append(errs, errors.New("SECURITY_HSTS_MAX_AGE: must not be negative"))
+	}
+	if c.HSTSPreload && (c.HSTSMaxAge < 31536000 || !c.HSTSIncludeSubdomains) {
+		errs = append(errs, errors.New("SECURITY_HSTS_PRELOAD: requires SECURITY_HSTS_INCLUDE_SUBDOMAINS and a max age of at least one year"))
+	}
+
+	switch c.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		errs = append(errs, fmt.Errorf("SECURITY_FRAME_OPTIONS: invalid value %q: expected DENY or SAMEORIGIN", c.FrameOptions))
+	}
+
+	if c.ReferrerPolicy != "" && !referrerPolicies[c.ReferrerPolicy] {
+		errs = append(errs, fmt.Errorf("SECURITY_REFERRER_POLICY: invalid policy %q", c.ReferrerPolicy))
+	}
+
+	if strings.ContainsAny(c.ContentSecurityPolicy, "\r\n") {
+		errs = append(errs, errors.New("SECURITY_CONTENT_SECURITY_POLICY: must be a single line"))
+	}
+
+	if c.MaxBodyBytes < 0 {
+		errs = append(errs, errors.New("SECURITY_MAX_BODY_BYTES: must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the whole configuration, reporting every problem found. Settings are named
+// by their environment variable.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(isPort(c.Server.Port), "SERVER_PORT: invalid port %q", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "SERVER_READ_TIMEOUT: must be positive")
+	check(c.Server.WriteTimeout > 0, "SERVER_WRITE_TIMEOUT: must be positive")
+	check(c.Server.ShutdownDelay >= 0, "SERVER_SHUTDOWN_DELAY: must not be negative")
+	check(oneOf(c.Server.Environment, "development", "test", "staging", "production"),
+		"APP_ENV: invalid environment %q: expected development, test, staging or production", c.Server.Environment)
+
+	check(oneOf(c.Database.Driver, "postgres", "sqlite"), "DB_DRIVER: invalid driver %q: expected postgres or sqlite", c.Database.Driver)
+	if c.Database.Driver == "sqlite" {
+		check(c.Database.SQLitePath != "", "DB_SQLITE_PATH: is required for the sqlite driver")
+		check(len(c.Database.Replicas) == 0, "DB_REPLICAS: read replicas require the postgres driver")
+		check(c.RateLimit.Store != "postgres", "RATE_LIMIT_STORE: the postgres store requires the postgres driver")
+	}
+	check(c.Database.Host != "", "DB_HOST: is required")
+	check(isPort(c.Database.Port), "DB_PORT: invalid port %q", c.Database.Port)
+	check(c.Database.User != "", "DB_USER: is required")
+	check(c.Database.DBName != "", "DB_NAME: is required")
+	check(oneOf(c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"DB_SSLMODE: invalid mode %q", c.Database.SSLMode)
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS: must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS: must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS: must not exceed DB_MAX_OPEN_CONNS")
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME: must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME: must not be negative")
+	check(c.Database.ConnectTimeout >= 0, "DB_CONNECT_TIMEOUT: must not be negative")
+	check(c.Database.StatementTimeoutMs >= 0, "DB_STATEMENT_TIMEOUT_MS: must not be negative")
+	check(c.Database.ConnectAttempts > 0, "DB_CONNECT_ATTEMPTS: must be positive")
+	check(c.Database.RetryBaseDelayMs > 0, "DB_RETRY_BASE_DELAY_MS: must be positive")
+	check(c.Database.RetryMaxDelayMs >= c.Database.RetryBaseDelayMs,
+		"DB_RETRY_MAX_DELAY_MS: must not be less than DB_RETRY_BASE_DELAY_MS")
+	if _, err := c.Database.ReplicaConfigs(); err != nil {
+		errs = append(errs, fmt.Errorf("DB_REPLICAS: %w", err))
+	}
+	check(c.Database.ReplicaCheckInterval > 0, "DB_REPLICA_CHECK_INTERVAL: must be positive")
+	check(c.Database.ReadYourWritesWindow >= 0, "DB_READ_YOUR_WRITES_WINDOW: must not be negative")
+
+	check(oneOf(strings.ToLower(c.Logging.Level), "debug", "info", "warn", "warning", "error"),
+		"LOG_LEVEL: invalid level %q: expected debug, info, warn or error", c.Logging.Level)
+	check(oneOf(strings.ToLower(c.Logging.Format), "json", "text"),
+		"LOG_FORMAT: invalid format %q: expected json or text", c.Logging.Format)
+
+	check(c.Auth.TokenSecret != "", "AUTH_TOKEN_SECRET: is required")
+	check(c.Auth.TokenTTL > 0, "AUTH_TOKEN_TTL: must be positive")
+	check(c.Auth.VerificationTokenTTL > 0, "AUTH_VERIFICATION_TOKEN_TTL: must be positive")
+	check(c.Auth.PasswordResetTTL > 0, "AUTH_PASSWORD_RESET_TTL: must be positive")
+	check(c.Auth.InvitationTTL > 0, "AUTH_INVITATION_TTL: must be positive")
+
+	check(oneOf(c.Mail.Driver, "log", "file", "smtp"), "MAIL_DRIVER: invalid driver %q: expected log, file or smtp", c.Mail.Driver)
+	check(c.Mail.From != "", "MAIL_FROM: is required")
+	check(isHTTPURL(c.Mail.BaseURL), "MAIL_BASE_URL: invalid URL %q", c.Mail.BaseURL)
+	if c.Mail.Driver == "smtp" {
+		check(c.Mail.SMTPHost != "", "SMTP_HOST: is required for the smtp driver")
+		check(isPort(c.Mail.SMTPPort), "SMTP_PORT: invalid port %q", c.Mail.SMTPPort)
+	}
+	if c.Mail.Driver == "file" {
+		check(c.Mail.FileDir != "", "MAIL_FILE_DIR: is required for the file driver")
+	}
+
+	check(c.Lockout.MaxAccountFailures > 0, "LOCKOUT_MAX_ACCOUNT_FAILURES: must be positive")
+	check(c.Lockout.MaxIPFailures > 0, "LOCKOUT_MAX_IP_FAILURES: must be positive")
+	check(c.Lockout.FailureWindow > 0, "LOCKOUT_FAILURE_WINDOW: must be positive")
+	check(c.Lockout.Duration > 0, "LOCKOUT_DURATION: must be positive")
+	check(c.Lockout.BaseDelayMs >= 0, "LOCKOUT_BASE_DELAY_MS: must not be negative")
+	check(c.Lockout.MaxDelayMs >= c.Lockout.BaseDelayMs, "LOCKOUT_MAX_DELAY_MS: must not be less than LOCKOUT_BASE_DELAY_MS")
+
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "otlp"),
+		"TRACING_EXPORTER: invalid exporter %q: expected none, stdout or otlp", c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME: is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO: must be between 0 and 1")
+
+	check(oneOf(c.RateLimit.Store, "memory", "postgres"),
+		"RATE_LIMIT_STORE: invalid store %q: expected memory or postgres", c.RateLimit.Store)
+	check(c.RateLimit.Default != "", "RATE_LIMIT_DEFAULT: is required")
+
+	check(c.Health.CheckTimeout > 0, "HEALTH_CHECK_TIMEOUT: must be positive")
+	check(c.Health.CacheTTL >= 0, "HEALTH_CACHE_TTL: must not be negative")
+	check(c.Health.SaturationThreshold > 0 && c.Health.SaturationThreshold <= 1,
+		"HEALTH_POOL_SATURATION_THRESHOLD: must be greater than 0 and at most 1")
+
+	check(c.Compression.MinSize >= 0, "COMPRESSION_MIN_SIZE: must not be negative")
+	check(c.Compression.Level >= -1 && c.Compression.Level <= 9, "COMPRESSION_LEVEL: must be between -1 and 9")
+	for _, contentType := range c.Compression.ContentTypes {
+		_, _, err := mime.ParseMediaType(contentType)
+		check(err == nil && strings.Contains(contentType, "/"), "COMPRESSION_TYPES: invalid content type %q", contentType)
+	}
+
+	check(oneOf(c.Panic.Reporter, "none", "file"), "PANIC_REPORTER: invalid reporter %q: expected none or file", c.Panic.Reporter)
+	if c.Panic.Reporter == "file" {
+		check(c.Panic.FilePath != "", "PANIC_REPORT_FILE: is required for the file reporter")
+	}
+
+	if c.UserCache.Enabled {
+		check(c.UserCache.Size > 0, "USER_CACHE_SIZE: must be positive")
+		check(c.UserCache.TTL > 0, "USER_CACHE_TTL: must be positive")
+		check(!c.UserCache.Notify || c.Database.Driver == "postgres", "USER_CACHE_NOTIFY: requires the postgres driver")
+	}
+
+	check(oneOf(c.Secrets.Provider, "env", "file", "vault"),
+		"SECRETS_PROVIDER: invalid provider %q: expected env, file or vault", c.Secrets.Provider)
+
+	// Production must not run with the credentials shipped for local development
+	if c.Server.Environment == "production" {
+		defaults := Defaults()
+		if c.Database.Driver == "postgres" {
+			check(c.Database.Password != "" && c.Database.Password != defaults.Database.Password,
+				"DB_PASSWORD: the default or an empty password cannot be used in production")
+		}
+		check(c.Auth.TokenSecret != defaults.Auth.TokenSecret, "AUTH_TOKEN_SECRET: the default secret cannot be used in production")
+		check(len(c.Auth.TokenSecret) >= 32, "AUTH_TOKEN_SECRET: must be at least 32 characters in production")
+	}
+
+	errs = append(errs, c.CORS.Validate(), c.Security.Validate(), c.CacheControl.Validate())
+
+	return errors.Join(errs...)
+}
+
+// oneOf reports whether value is one of allowed
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// isPort reports whether s is a TCP port number
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"goapi/pkg/logger"
+)
+
+// fileCheckInterval is how often the config file is checked for changes
+const fileCheckInterval = 2 * time.Second
+
+// Watcher reloads the configuration on SIGHUP and when the config file changes. A new
+// configuration is applied only when it is valid and every changed setting is reloadable;
+// otherwise the running configuration is kept.
+type Watcher struct {
+	opts  Options
+	apply func(*Config) error
+	log   logger.Logger
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+}
+
+// NewWatcher creates a watcher for the configuration loaded from opts. apply receives each
+// accepted configuration and installs its reloadable settings; if it fails nothing is changed.
+func NewWatcher(opts Options, current *Config, apply func(*Config) error, log logger.Logger) *Watcher {
+	w := &Watcher{opts: opts, apply: apply, log: log, current: current}
+	w.modTime, _ = w.fileModTime()
+	return w
+}
+
+// Current returns the configuration in effect
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Run reloads on SIGHUP and config file changes until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var fileChanges <-chan time.Time
+	if w.opts.File != "" {
+		ticker := time.NewTicker(fileCheckInterval)
+		defer ticker.Stop()
+		fileChanges = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.log.Info("Reloading configuration", "trigger", "SIGHUP")
+			w.Reload()
+		case <-fileChanges:
+			modTime, err := w.fileModTime()
+			if err != nil || modTime.Equal(w.modTime) {
+				continue
+			}
+			w.modTime = modTime
+			w.log.Info("Reloading configuration", "trigger", "file", "file", w.opts.File)
+			w.Reload()
+		}
+	}
+}
+
+// Reload loads the configuration again and applies it, logging the changed settings.
+// The returned error, also logged, explains why a configuration was rejected.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, err := Load(w.opts)
+	if err != nil {
+		w.log.Error("Configuration reload rejected", "error", err)
+		return err
+	}
+
+	changes := Diff(w.current, next)
+	if len(changes) == 0 {
+		w.log.Info("Configuration unchanged")
+		return nil
+	}
+
+	var rejected []error
+	described := make([]string, len(changes))
+	for i, change := range changes {
+		described[i] = change.String()
+		if !change.Reloadable {
+			rejected = append(rejected, fmt.Errorf("%s: cannot be changed without a restart", change.Key))
+		}
+	}
+	if err := errors.Join(rejected...); err != nil {
+		w.log.Error("Configuration reload rejected", "error", err, "changes", described)
+		return err
+	}
+
+	if err := w.apply(next); err != nil {
+		w.log.Error("Configuration reload rejected", "error", err, "changes", described)
+		return err
+	}
+
+	w.current = next
+	w.log.Info("Configuration reloaded", "changes", described)
+	return nil
+}
+
+// fileModTime returns the config file's modification time
+func (w *Watcher) fileModTime() (time.Time, error) {
+	if w.opts.File == "" {
+		return time.Time{}, nil
+	}
+
+	info, err := os.Stat(w.opts.File)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
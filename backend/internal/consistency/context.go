@@ -0,0 +1,79 @@
+package consistency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Session identifies whose writes a request must be able to read back. Requests of the same
+// key, such as the same user, share the read-your-writes guarantee across requests; a session
+// without a key only covers its own request.
+type Session struct {
+	Key   string
+	wrote atomic.Bool
+}
+
+type sessionKey struct{}
+
+type primaryKey struct{}
+
+type pinKey struct{}
+
+// WithSession returns a copy of ctx carrying a new session for key
+func WithSession(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &Session{Key: key})
+}
+
+// SessionFromContext returns the session carried by ctx, if any
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// MarkWrite records that the session wrote to the primary
+func (s *Session) MarkWrite() {
+	s.wrote.Store(true)
+}
+
+// Wrote reports whether the session wrote to the primary during this request
+func (s *Session) Wrote() bool {
+	return s.wrote.Load()
+}
+
+// WithPrimary returns a copy of ctx whose reads always go to the primary
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryRequired reports whether reads for ctx must go to the primary
+func PrimaryRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(primaryKey{}).(bool)
+	return required
+}
+
+// Pin keeps the reads of a request on one database, so results of separate queries describe
+// the same state, or the later ones a newer state when the database has to change
+type Pin struct {
+	once   sync.Once
+	target string
+}
+
+// WithPinnedReads returns a copy of ctx whose reads all go to the database the first read uses
+func WithPinnedReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinKey{}, &Pin{})
+}
+
+// PinFromContext returns the pin carried by ctx, if any
+func PinFromContext(ctx context.Context) (*Pin, bool) {
+	p, ok := ctx.Value(pinKey{}).(*Pin)
+	return p, ok
+}
+
+// Resolve returns the pinned database, calling choose to pick it on first use
+func (p *Pin) Resolve(choose func() string) string {
+	p.once.Do(func() {
+		p.target = choose()
+	})
+	return p.target
+}
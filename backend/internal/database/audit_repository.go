@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"goapi/internal/models"
+)
+
+// AuditRepository handles audit event storage
+type AuditRepository struct {
+	db *DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record stores an audit event
+func (r *AuditRepository) Record(ctx context.Context, event models.AuditEvent) (err error) {
+	defer r.db.observe(ctx, "AuditRepository.Record")(&err)
+
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit details: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_events (event_type, user_id, actor_id, ip, details)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+	`
+
+	_, err = r.db.DB.ExecContext(ctx, query, event.Type, event.UserID, event.ActorID, event.IP, string(details))
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,320 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goapi/internal/models"
+)
+
+// AuthRepository handles credential and MFA-related database operations
+type AuthRepository struct {
+	db *DB
+}
+
+// NewAuthRepository creates a new auth repository
+func NewAuthRepository(db *DB) *AuthRepository {
+	return &AuthRepository{db: db}
+}
+
+// GetCredentialsByEmail retrieves the login credentials for a user by email within an organization
+func (r *AuthRepository) GetCredentialsByEmail(ctx context.Context, orgID int, email string) (_ *models.Credentials, err error) {
+	defer r.db.observe(ctx, "AuthRepository.GetCredentialsByEmail")(&err)
+
+	query := `
+		SELECT id, org_id, email, role, COALESCE(password_hash, '')
+		FROM users
+		WHERE email = $1 AND org_id = $2
+	`
+
+	var creds models.Credentials
+	err = r.db.DB.QueryRowContext(ctx, query, email, orgID).Scan(
+		&creds.UserID, &creds.OrgID, &creds.Email, &creds.Role, &creds.PasswordHash,
+	)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, fmt.Errorf("user with email %s %w", email, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	return &creds, nil
+}
+
+// GetMFA retrieves a user's MFA settings, returning nil when the user has never enrolled
+func (r *AuthRepository) GetMFA(ctx context.Context, userID int) (_ *models.MFASettings, err error) {
+	defer r.db.observe(ctx, "AuthRepository.GetMFA")(&err)
+
+	query := `
+		SELECT user_id, secret, enabled_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	var mfa models.MFASettings
+	err = r.db.DB.QueryRowContext(ctx, query, userID).Scan(&mfa.UserID, &mfa.Secret, &mfa.EnabledAt)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get mfa settings: %w", err)
+	}
+
+	return &mfa, nil
+}
+
+// SavePendingMFA stores a new unconfirmed TOTP secret, replacing any previous pending one. It
+// returns ErrMFAEnabled if the user's MFA is already confirmed.
+func (r *AuthRepository) SavePendingMFA(ctx context.Context, userID int, secret string) (err error) {
+	defer r.db.observe(ctx, "AuthRepository.SavePendingMFA")(&err)
+
+	query := `
+		INSERT INTO user_mfa (user_id, secret, enabled_at)
+		VALUES ($1, $2, NULL)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled_at = NULL, last_totp_step = NULL, created_at = CURRENT_TIMESTAMP
+		WHERE user_mfa.enabled_at IS NULL
+	`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa secret: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrMFAEnabled
+	}
+
+	return nil
+}
+
+// EnableMFA confirms a pending enrollment and stores the hashed recovery codes
+func (r *AuthRepository) EnableMFA(ctx context.Context, userID int, codeHashes []string) (err error) {
+	defer r.db.observe(ctx, "AuthRepository.EnableMFA")(&err)
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE user_mfa SET enabled_at = CURRENT_TIMESTAMP WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and stores new hashed ones
+func (r *AuthRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) (err error) {
+	defer r.db.observe(ctx, "AuthRepository.ReplaceRecoveryCodes")(&err)
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used, reporting whether one matched
+func (r *AuthRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (_ bool, err error) {
+	defer r.db.observe(ctx, "AuthRepository.UseRecoveryCode")(&err)
+
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code, returning false if that step or a
+// later one was already used
+func (r *AuthRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (_ bool, err error) {
+	defer r.db.observe(ctx, "AuthRepository.UseTOTPStep")(&err)
+
+	query := `
+		UPDATE user_mfa
+		SET last_totp_step = $2
+		WHERE user_id = $1 AND (last_totp_step IS NULL OR last_totp_step < $2)
+	`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// VerifyEmail consumes a verification token and marks the email verified if it is still the user's address
+func (r *AuthRepository) VerifyEmail(ctx context.Context, tokenID string, expiresAt time.Time, userID int, email string) (err error) {
+	defer r.db.observe(ctx, "AuthRepository.VerifyEmail")(&err)
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := consumeToken(ctx, tx, tokenID, expiresAt); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND email = $2
+	`
+
+	result, err := tx.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.db.markWrite(ctx)
+	r.db.usersChanged(ctx, userID)
+	return nil
+}
+
+// ResetPassword consumes a password reset token and stores the new password hash
+func (r *AuthRepository) ResetPassword(ctx context.Context, tokenID string, expiresAt time.Time, userID int, passwordHash string) (err error) {
+	defer r.db.observe(ctx, "AuthRepository.ResetPassword")(&err)
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := consumeToken(ctx, tx, tokenID, expiresAt); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.db.markWrite(ctx)
+	r.db.usersChanged(ctx, userID)
+	return nil
+}
+
+// consumeToken records a single-use token, failing with ErrTokenUsed if it was already consumed.
+// Expired records are pruned opportunistically since their tokens can no longer be parsed.
+func consumeToken(ctx context.Context, tx *sql.Tx, tokenID string, expiresAt time.Time) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM consumed_tokens WHERE expires_at < $1`, dbTime(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to prune consumed tokens: %w", err)
+	}
+
+	query := `
+		INSERT INTO consumed_tokens (id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	result, err := tx.ExecContext(ctx, query, tokenID, dbTime(expiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenUsed
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes deletes and re-inserts recovery codes within a transaction
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
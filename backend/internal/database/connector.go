@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"goapi/internal/config"
+
+	"github.com/lib/pq"
+)
+
+// connector opens each connection with the password currently held by the secret provider, so
+// new connections pick up a rotated database password without a restart
+type connector struct {
+	cfg     config.DatabaseConfig
+	secrets config.SecretProvider
+}
+
+// newConnector creates a connector; without a provider the configured password is always used
+func newConnector(cfg config.DatabaseConfig, secrets config.SecretProvider) *connector {
+	return &connector{cfg: cfg, secrets: secrets}
+}
+
+// Connect opens a connection with the current credentials
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.url(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pqConnector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return pqConnector.Connect(ctx)
+}
+
+// url returns the connection URL with the current credentials
+func (c *connector) url(ctx context.Context) (string, error) {
+	cfg := c.cfg
+	if c.secrets != nil {
+		password, err := c.secrets.Secret(ctx, "db_password")
+		switch {
+		case err == nil:
+			cfg.Password = password
+		case !errors.Is(err, config.ErrSecretNotFound):
+			return "", fmt.Errorf("failed to read database password: %w", err)
+		}
+	}
+	return cfg.URL(), nil
+}
+
+// Driver returns the underlying postgres driver
+func (c *connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
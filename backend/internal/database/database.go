@@ -1,57 +1,148 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"time"
 
 	"goapi/internal/config"
+	"goapi/internal/metrics"
+	"goapi/internal/tracing"
+	"goapi/pkg/logger"
 
 	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 	_ "github.com/lib/pq"
 )
 
 // DB wraps the sql.DB with additional methods
 type DB struct {
 	*sql.DB
+	log      logger.Logger
+	metrics  *metrics.Metrics
+	replicas *replicaSet
+	dialect  dialect
+	stmts    *stmtCache
+	// connector opens postgres connections; nil for sqlite
+	connector *connector
+
+	// onUsersChanged, when set, is told about every write to users; see usersChanged
+	onUsersChanged func(ctx context.Context, id int)
 }
 
-// NewDatabase creates a new database connection
-func NewDatabase(cfg *config.Config) (*DB, error) {
-	// Open database connection
-	db, err := sql.Open("postgres", cfg.GetDatabaseURL())
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+// NewDatabase creates a new database connection for the configured driver. When secrets is not
+// nil the postgres password is read from it for every new connection, falling back to the
+// configured password.
+func NewDatabase(cfg *config.Config, log logger.Logger, m *metrics.Metrics, secrets config.SecretProvider) (*DB, error) {
+	if cfg.Database.Driver == "sqlite" {
+		return newSQLiteDatabase(cfg.Database, log, m)
 	}
 
+	// Open database connection
+	conn := newConnector(cfg.Database, secrets)
+	db := sql.OpenDB(conn)
+
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(db, cfg.Database)
 
-	// Test the connection
-	if err = db.Ping(); err != nil {
+	// Wait for the database, which may still be starting
+	if err := connectWithRetry(context.Background(), db, cfg.Database, log); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Database connected successfully!")
+	log.Info("Database connected", "host", cfg.Database.Host, "database", cfg.Database.DBName)
 
 	// Create tables
-	if err := createTables(db); err != nil {
+	if err := createTables(db, log); err != nil {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	return &DB{db}, nil
+	// Export connection pool statistics
+	m.RegisterDB(db, cfg.Database.DBName)
+
+	// Connect read replicas, which serve user reads when healthy
+	replicas, err := openReplicas(cfg.Database, log, m, secrets)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open read replicas: %w", err)
+	}
+	if replicas != nil {
+		log.Info("Database read replicas configured", "replicas", replicas.names())
+	}
+
+	return &DB{DB: db, log: log, metrics: m, replicas: replicas, dialect: postgresDialect, stmts: newStmtCache(db), connector: conn}, nil
+}
+
+// usersChanged reports a successful write to the user with the given ID to the user cache, if
+// any; an ID of 0 means any user may have changed
+func (db *DB) usersChanged(ctx context.Context, id int) {
+	if db.onUsersChanged != nil {
+		db.onUsersChanged(ctx, id)
+	}
+}
+
+// configurePool applies the connection pool settings to db
+func configurePool(db *sql.DB, cfg config.DatabaseConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+}
+
+// connectWithRetry pings the database until it answers, waiting with exponential backoff and
+// jitter between attempts
+func connectWithRetry(ctx context.Context, db *sql.DB, cfg config.DatabaseConfig, log logger.Logger) error {
+	delay := time.Duration(cfg.RetryBaseDelayMs) * time.Millisecond
+	maxDelay := time.Duration(cfg.RetryMaxDelayMs) * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx, db, cfg)
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		// Sleep between half and all of the current delay so restarting replicas spread out
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		log.Warn("Database not reachable, retrying", "attempt", attempt, "retry_in_ms", wait.Milliseconds(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// ping checks the connection, bounded by the connect timeout
+func ping(ctx context.Context, db *sql.DB, cfg config.DatabaseConfig) error {
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.ConnectTimeout)*time.Second)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
 }
 
 // createTables creates all necessary tables
-func createTables(db *sql.DB) error {
+func createTables(db *sql.DB, log logger.Logger) error {
 	query := `
 	CREATE TABLE IF NOT EXISTS users (
 		id SERIAL PRIMARY KEY,
 		name VARCHAR(100) NOT NULL,
-		email VARCHAR(100) UNIQUE NOT NULL,
+		email VARCHAR(100) NOT NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)`
@@ -83,20 +174,193 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create updated_at trigger: %w", err)
 	}
 
-	log.Println("Database tables created successfully!")
+	// Create organizations and scope users to them, making email unique per organization
+	tenancyQuery := `
+	CREATE TABLE IF NOT EXISTS organizations (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		slug VARCHAR(63) UNIQUE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
+	CREATE TRIGGER update_organizations_updated_at
+		BEFORE UPDATE ON organizations
+		FOR EACH ROW
+		EXECUTE FUNCTION update_updated_at_column();
+
+	INSERT INTO organizations (name, slug) VALUES ('Default', 'default') ON CONFLICT (slug) DO NOTHING;
+
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+	UPDATE users SET org_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE org_id IS NULL;
+	ALTER TABLE users ALTER COLUMN org_id SET NOT NULL;
+
+	ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_org_email ON users(org_id, email);
+	`
+
+	_, err = db.Exec(tenancyQuery)
+	if err != nil {
+		return fmt.Errorf("failed to create organizations: %w", err)
+	}
+
+	// Add authentication columns to existing deployments
+	authColumnsQuery := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'user';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+	`
+
+	_, err = db.Exec(authColumnsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to add authentication columns: %w", err)
+	}
+
+	authTablesQuery := `
+	CREATE TABLE IF NOT EXISTS user_mfa (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		enabled_at TIMESTAMP,
+		last_totp_step BIGINT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	ALTER TABLE user_mfa ADD COLUMN IF NOT EXISTS last_totp_step BIGINT;
+
+	CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+	CREATE TABLE IF NOT EXISTS consumed_tokens (
+		id VARCHAR(64) PRIMARY KEY,
+		expires_at TIMESTAMPTZ NOT NULL,
+		consumed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS login_throttles (
+		key VARCHAR(255) PRIMARY KEY,
+		failures INTEGER NOT NULL DEFAULT 0,
+		window_started_at TIMESTAMPTZ NOT NULL,
+		last_failure_at TIMESTAMPTZ NOT NULL,
+		locked_until TIMESTAMPTZ
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id SERIAL PRIMARY KEY,
+		event_type VARCHAR(64) NOT NULL,
+		user_id INTEGER,
+		actor_id INTEGER,
+		ip VARCHAR(64),
+		details JSONB,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id);
+
+	CREATE TABLE IF NOT EXISTS invitations (
+		id SERIAL PRIMARY KEY,
+		org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		email VARCHAR(100) NOT NULL,
+		role VARCHAR(32) NOT NULL DEFAULT 'user',
+		token_id VARCHAR(64) UNIQUE NOT NULL,
+		invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		accepted_at TIMESTAMPTZ,
+		revoked_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_invitations_org_id ON invitations(org_id);
+	`
+
+	_, err = db.Exec(authTablesQuery)
+	if err != nil {
+		return fmt.Errorf("failed to create auth tables: %w", err)
+	}
+
+	// Token buckets shared by all replicas when rate limiting uses the postgres store
+	rateLimitQuery := `
+	CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+		key VARCHAR(255) PRIMARY KEY,
+		tokens DOUBLE PRECISION NOT NULL,
+		allowed BOOLEAN NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+	`
+
+	_, err = db.Exec(rateLimitQuery)
+	if err != nil {
+		return fmt.Errorf("failed to create rate limit tables: %w", err)
+	}
+
+	log.Info("Database tables created")
 	return nil
 }
 
-// Close closes the database connection
+// observe is deferred by repository methods to report the outcome of a call:
+//
+//	defer r.db.observe(ctx, "UserRepository.GetAll")(&err)
+//
+// Each call gets a client span and its latency is recorded per operation; failures are logged
+// with the request's correlation fields. Errors that do not wrap a cause, such as "email already
+// exists", and the expected errors, such as ErrNotFound, are outcomes for the caller to handle
+// and count as successes.
+func (db *DB) observe(ctx context.Context, op string) func(*error) {
+	start := time.Now()
+	_, span := tracing.Tracer().Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(db.dialect.system, semconv.CodeFunction(op)),
+	)
+
+	return func(errp *error) {
+		defer span.End()
+
+		err := *errp
+		failed := err != nil && errors.Unwrap(err) != nil && !isExpected(err)
+
+		db.metrics.ObserveDBOperation(op, time.Since(start), failed)
+		if failed {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			db.log.WithContext(ctx).Error("Database operation failed", "operation", op, "error", err)
+		}
+	}
+}
+
+// Close closes the prepared statements, the database connection and any read replicas
 func (db *DB) Close() error {
+	if err := db.stmts.close(); err != nil {
+		db.log.Error("Failed to close prepared statements", "error", err)
+	}
+	if db.replicas != nil {
+		if err := db.replicas.close(); err != nil {
+			db.log.Error("Failed to close read replicas", "error", err)
+		}
+	}
 	return db.DB.Close()
 }
 
-// IsUniqueConstraintError checks if the error is a unique constraint violation
+// IsUniqueConstraintError checks if the error is a unique constraint violation, for either driver
 func IsUniqueConstraintError(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
 		return pqErr.Code == "23505" // unique_violation
 	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
 	return false
 }
 
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// dialect describes how a supported database differs from the others. Queries use $1 style
+// placeholders, RETURNING and ON CONFLICT, which both PostgreSQL and SQLite understand.
+type dialect struct {
+	name string
+	// system identifies the database in trace spans
+	system attribute.KeyValue
+	// now is the SQL expression for the current time at the precision updated_at is stored
+	now string
+}
+
+var (
+	postgresDialect = dialect{name: "postgres", system: semconv.DBSystemPostgreSQL, now: "CURRENT_TIMESTAMP"}
+	sqliteDialect   = dialect{name: "sqlite", system: semconv.DBSystemSqlite, now: "strftime('%Y-%m-%d %H:%M:%f', 'now')"}
+)
+
+// dbTime returns t as it must be bound to a query. SQLite stores times as text and compares
+// them as strings, which only orders them correctly when all of them are in the same zone, so
+// times are bound in UTC. Comparisons with the current time bind dbTime(time.Now()) rather than
+// using CURRENT_TIMESTAMP, whose text has a different format.
+func dbTime(t time.Time) time.Time {
+	return t.UTC()
+}
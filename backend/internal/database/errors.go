@@ -0,0 +1,31 @@
+package database
+
+import "errors"
+
+// ErrNotFound is wrapped by the errors of repository methods whose row does not exist, so
+// callers can tell it apart with errors.Is while the message still names what is missing:
+//
+//	fmt.Errorf("user with ID %d %w", id, ErrNotFound) // "user with ID 1 not found"
+var ErrNotFound = errors.New("not found")
+
+// ErrMFAEnabled is returned by SavePendingMFA when the user's MFA is already confirmed
+var ErrMFAEnabled = errors.New("mfa already enabled")
+
+// ErrTokenUsed is returned when a single-use token has already been consumed
+var ErrTokenUsed = errors.New("token already used")
+
+// ErrSlugExists is returned when an organization's slug is already taken
+var ErrSlugExists = errors.New("slug already exists")
+
+// expectedErrors are outcomes the caller handles, so observe does not count them as failures
+var expectedErrors = []error{ErrNotFound, ErrMFAEnabled, ErrTokenUsed, ErrSlugExists}
+
+// isExpected reports whether err is one of the expected outcomes
+func isExpected(err error) bool {
+	for _, expected := range expectedErrors {
+		if errors.Is(err, expected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// migratedTables lists the tables createTables is expected to have created
+var migratedTables = []string{
+	"users",
+	"organizations",
+	"user_mfa",
+	"mfa_recovery_codes",
+	"consumed_tokens",
+	"login_throttles",
+	"audit_events",
+	"invitations",
+	"rate_limit_buckets",
+}
+
+// CheckConnection pings the database
+func (db *DB) CheckConnection(ctx context.Context) error {
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// CheckMigrations verifies that every table created at startup exists
+func (db *DB) CheckMigrations(ctx context.Context) error {
+	query := `SELECT name FROM unnest($1::text[]) AS name WHERE to_regclass(name) IS NULL`
+	args := []interface{}{pq.Array(migratedTables)}
+	if db.dialect.name == sqliteDialect.name {
+		query = `SELECT value FROM json_each($1) WHERE value NOT IN (SELECT name FROM sqlite_master WHERE type = 'table')`
+		tables, _ := json.Marshal(migratedTables)
+		args = []interface{}{string(tables)}
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to check tables: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		missing = append(missing, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// CheckPoolSaturation returns a check that fails when the share of open connections in use
+// reaches threshold, meaning new queries are about to wait for a connection
+func (db *DB) CheckPoolSaturation(threshold float64) func(context.Context) error {
+	return func(ctx context.Context) error {
+		stats := db.Stats()
+		if stats.MaxOpenConnections == 0 {
+			return nil
+		}
+
+		saturation := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		if saturation >= threshold {
+			return fmt.Errorf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goapi/internal/models"
+)
+
+// InvitationRepository handles invitation-related database operations
+type InvitationRepository struct {
+	db *DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create stores a new invitation, revoking any pending invitation for the same email in the organization
+func (r *InvitationRepository) Create(ctx context.Context, inv models.Invitation) (_ *models.Invitation, err error) {
+	defer r.db.observe(ctx, "InvitationRepository.Create")(&err)
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	revokeQuery := `
+		UPDATE invitations
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE org_id = $1 AND email = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+
+	if _, err := tx.ExecContext(ctx, revokeQuery, inv.OrgID, inv.Email); err != nil {
+		return nil, fmt.Errorf("failed to revoke previous invitations: %w", err)
+	}
+
+	query := `
+		INSERT INTO invitations (org_id, email, role, token_id, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, org_id, email, role, token_id, invited_by, expires_at, accepted_at, revoked_at, created_at
+	`
+
+	var created models.Invitation
+	err = tx.QueryRowContext(ctx, query, inv.OrgID, inv.Email, inv.Role, inv.TokenID, inv.InvitedBy, dbTime(inv.ExpiresAt)).Scan(
+		&created.ID, &created.OrgID, &created.Email, &created.Role, &created.TokenID, &created.InvitedBy,
+		&created.ExpiresAt, &created.AcceptedAt, &created.RevokedAt, &created.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetPending retrieves the unaccepted, unrevoked and unexpired invitations of an organization
+func (r *InvitationRepository) GetPending(ctx context.Context, orgID int) (_ []models.Invitation, err error) {
+	defer r.db.observe(ctx, "InvitationRepository.GetPending")(&err)
+
+	query := `
+		SELECT id, org_id, email, role, token_id, invited_by, expires_at, accepted_at, revoked_at, created_at
+		FROM invitations
+		WHERE org_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, orgID, dbTime(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []models.Invitation
+	for rows.Next() {
+		var inv models.Invitation
+		err := rows.Scan(
+			&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenID, &inv.InvitedBy,
+			&inv.ExpiresAt, &inv.AcceptedAt, &inv.RevokedAt, &inv.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// GetByTokenID retrieves an invitation by the identifier embedded in its token
+func (r *InvitationRepository) GetByTokenID(ctx context.Context, tokenID string) (_ *models.Invitation, err error) {
+	defer r.db.observe(ctx, "InvitationRepository.GetByTokenID")(&err)
+
+	query := `
+		SELECT id, org_id, email, role, token_id, invited_by, expires_at, accepted_at, revoked_at, created_at
+		FROM invitations
+		WHERE token_id = $1
+	`
+
+	var inv models.Invitation
+	err = r.db.DB.QueryRowContext(ctx, query, tokenID).Scan(
+		&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenID, &inv.InvitedBy,
+		&inv.ExpiresAt, &inv.AcceptedAt, &inv.RevokedAt, &inv.CreatedAt,
+	)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, fmt.Errorf("invitation %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return &inv, nil
+}
+
+// Revoke revokes a pending invitation within an organization
+func (r *InvitationRepository) Revoke(ctx context.Context, orgID, id int) (err error) {
+	defer r.db.observe(ctx, "InvitationRepository.Revoke")(&err)
+
+	query := `
+		UPDATE invitations
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("invitation %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+// Claim returns the claim UserRepository.CreateVerified makes for a user accepting the
+// invitation: it marks the invitation accepted, failing with ErrNotFound unless it was still
+// pending. An invitation accepted, revoked or expired meanwhile therefore creates no one, and
+// two acceptances of the same invitation cannot both succeed.
+func (r *InvitationRepository) Claim(id int) Claim {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		query := `
+			UPDATE invitations
+			SET accepted_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $2
+		`
+
+		result, err := tx.ExecContext(ctx, query, id, dbTime(time.Now()))
+		if err != nil {
+			return fmt.Errorf("failed to accept invitation: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected != 1 {
+			return fmt.Errorf("invitation %w", ErrNotFound)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goapi/internal/models"
+)
+
+// LockoutRepository handles failed login tracking
+type LockoutRepository struct {
+	db *DB
+}
+
+// NewLockoutRepository creates a new lockout repository
+func NewLockoutRepository(db *DB) *LockoutRepository {
+	return &LockoutRepository{db: db}
+}
+
+// Reserve counts an attempt against a key before its credentials are checked, starting a new
+// window when the previous one began before windowStart. A locked key is left as it is. The
+// returned row holds the count including this attempt and the time of the last recorded failure,
+// so concurrent attempts each see a different count.
+func (r *LockoutRepository) Reserve(ctx context.Context, key string, now, windowStart time.Time) (_ *models.LoginThrottle, err error) {
+	defer r.db.observe(ctx, "LockoutRepository.Reserve")(&err)
+
+	query := `
+		INSERT INTO login_throttles (key, failures, window_started_at, last_failure_at)
+		VALUES ($1, 1, $2, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.locked_until > $2 THEN login_throttles.failures
+				WHEN login_throttles.window_started_at < $3 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			window_started_at = CASE
+				WHEN login_throttles.locked_until > $2 THEN login_throttles.window_started_at
+				WHEN login_throttles.window_started_at < $3 THEN $2
+				ELSE login_throttles.window_started_at
+			END
+		RETURNING key, failures, window_started_at, last_failure_at, locked_until
+	`
+
+	var t models.LoginThrottle
+	err = r.db.DB.QueryRowContext(ctx, query, key, dbTime(now), dbTime(windowStart)).Scan(
+		&t.Key, &t.Failures, &t.WindowStartedAt, &t.LastFailureAt, &t.LockedUntil,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve login attempt: %w", err)
+	}
+
+	return &t, nil
+}
+
+// Release gives back an attempt reserved against a key that was refused or did not fail
+func (r *LockoutRepository) Release(ctx context.Context, key string) (err error) {
+	defer r.db.observe(ctx, "LockoutRepository.Release")(&err)
+
+	query := `
+		UPDATE login_throttles
+		SET failures = failures - 1
+		WHERE key = $1 AND failures > 0
+	`
+
+	_, err = r.db.DB.ExecContext(ctx, query, key)
+	if err != nil {
+		return fmt.Errorf("failed to release login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailure keeps the attempt reserved against a key as a failure, returning nil when the
+// key was cleared meanwhile
+func (r *LockoutRepository) RecordFailure(ctx context.Context, key string, now time.Time) (_ *models.LoginThrottle, err error) {
+	defer r.db.observe(ctx, "LockoutRepository.RecordFailure")(&err)
+
+	query := `
+		UPDATE login_throttles
+		SET last_failure_at = $2
+		WHERE key = $1
+		RETURNING key, failures, window_started_at, last_failure_at, locked_until
+	`
+
+	var t models.LoginThrottle
+	err = r.db.DB.QueryRowContext(ctx, query, key, dbTime(now)).Scan(
+		&t.Key, &t.Failures, &t.WindowStartedAt, &t.LastFailureAt, &t.LockedUntil,
+	)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return &t, nil
+}
+
+// Lock locks a key until the given time and resets its failure count
+func (r *LockoutRepository) Lock(ctx context.Context, key string, until time.Time) (err error) {
+	defer r.db.observe(ctx, "LockoutRepository.Lock")(&err)
+
+	query := `
+		UPDATE login_throttles
+		SET locked_until = $2, failures = 0
+		WHERE key = $1
+	`
+
+	_, err = r.db.DB.ExecContext(ctx, query, key, dbTime(until))
+	if err != nil {
+		return fmt.Errorf("failed to lock login throttle: %w", err)
+	}
+
+	return nil
+}
+
+// Clear removes all throttle state for a key
+func (r *LockoutRepository) Clear(ctx context.Context, key string) (err error) {
+	defer r.db.observe(ctx, "LockoutRepository.Clear")(&err)
+
+	_, err = r.db.DB.ExecContext(ctx, `DELETE FROM login_throttles WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+
+	return nil
+}
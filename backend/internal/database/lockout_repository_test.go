@@ -0,0 +1,17 @@
+package database_test
+
+import (
+	"testing"
+
+	"goapi/internal/database/storetest"
+)
+
+// TestLockoutRepository runs the lockout conformance suite against every backend. The postgres
+// run is skipped unless TEST_DATABASE_URL names a database.
+func TestLockoutRepository(t *testing.T) {
+	for _, driver := range []string{"sqlite", "postgres"} {
+		t.Run(driver, func(t *testing.T) {
+			storetest.LockoutRepository(t, storetest.Open(t, driver))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"goapi/internal/models"
+)
+
+// OrganizationRepository handles organization-related database operations
+type OrganizationRepository struct {
+	db *DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// GetAll retrieves all organizations
+func (r *OrganizationRepository) GetAll(ctx context.Context) (_ []models.Organization, err error) {
+	defer r.db.observe(ctx, "OrganizationRepository.GetAll")(&err)
+
+	query := `
+		SELECT id, name, slug, created_at, updated_at
+		FROM organizations
+		ORDER BY name
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var org models.Organization
+		err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// GetByID retrieves an organization by ID
+func (r *OrganizationRepository) GetByID(ctx context.Context, id int) (_ *models.Organization, err error) {
+	defer r.db.observe(ctx, "OrganizationRepository.GetByID")(&err)
+
+	query := `
+		SELECT id, name, slug, created_at, updated_at
+		FROM organizations
+		WHERE id = $1
+	`
+
+	var org models.Organization
+	err = r.db.DB.QueryRowContext(ctx, query, id).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, fmt.Errorf("organization with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// GetBySlug retrieves an organization by slug
+func (r *OrganizationRepository) GetBySlug(ctx context.Context, slug string) (_ *models.Organization, err error) {
+	defer r.db.observe(ctx, "OrganizationRepository.GetBySlug")(&err)
+
+	query := `
+		SELECT id, name, slug, created_at, updated_at
+		FROM organizations
+		WHERE slug = $1
+	`
+
+	var org models.Organization
+	err = r.db.DB.QueryRowContext(ctx, query, slug).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, fmt.Errorf("organization with slug %s %w", slug, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// Create creates a new organization
+func (r *OrganizationRepository) Create(ctx context.Context, req models.CreateOrganizationRequest) (_ *models.Organization, err error) {
+	defer r.db.observe(ctx, "OrganizationRepository.Create")(&err)
+
+	query := `
+		INSERT INTO organizations (name, slug)
+		VALUES ($1, $2)
+		RETURNING id, name, slug, created_at, updated_at
+	`
+
+	var org models.Organization
+	err = r.db.DB.QueryRowContext(ctx, query, req.Name, req.Slug).Scan(
+		&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt,
+	)
+
+	if err != nil {
+		if IsUniqueConstraintError(err) {
+			return nil, ErrSlugExists
+		}
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// Update updates an existing organization
+func (r *OrganizationRepository) Update(ctx context.Context, id int, req models.UpdateOrganizationRequest) (_ *models.Organization, err error) {
+	defer r.db.observe(ctx, "OrganizationRepository.Update")(&err)
+
+	// SQLite's updated_at trigger runs after the update, so set it here for RETURNING to see it
+	query := `
+		UPDATE organizations
+		SET name = $1, slug = $2, updated_at = ` + r.db.dialect.now + `
+		WHERE id = $3
+		RETURNING id, name, slug, created_at, updated_at
+	`
+
+	var org models.Organization
+	err = r.db.DB.QueryRowContext(ctx, query, req.Name, req.Slug, id).Scan(
+		&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt,
+	)
+
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, fmt.Errorf("organization with ID %d %w", id, ErrNotFound)
+		}
+		if IsUniqueConstraintError(err) {
+			return nil, ErrSlugExists
+		}
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// Delete deletes an organization and, through the foreign key, all of its users
+func (r *OrganizationRepository) Delete(ctx context.Context, id int) (err error) {
+	defer r.db.observe(ctx, "OrganizationRepository.Delete")(&err)
+
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("organization with ID %d %w", id, ErrNotFound)
+	}
+
+	r.db.usersChanged(ctx, 0)
+	return nil
+}
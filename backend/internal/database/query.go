@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// stmtCache prepares each query once per pool. database/sql then prepares the statement once on
+// every connection it runs on and reuses it, so repeated queries skip parsing and planning.
+// Queries are keyed by their text, which must not vary with the arguments.
+type stmtCache struct {
+	db *sql.DB
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// newStmtCache creates an empty statement cache for db
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepare returns the cached statement for query, preparing it on first use
+func (c *stmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	stmt, ok := c.stmts[query]
+	c.mu.Unlock()
+	if ok {
+		return stmt, nil
+	}
+
+	// Prepare without holding the lock; when two callers race, the first statement stored wins
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.stmts[query]; ok {
+		stmt.Close()
+		return existing, nil
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// query runs a prepared query. When the statement cannot be prepared the query runs unprepared,
+// which reports the same error in the place callers expect it.
+func (c *stmtCache) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// queryRow is query for queries returning at most one row
+func (c *stmtCache) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// exec is query for statements returning no rows
+func (c *stmtCache) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return c.db.ExecContext(ctx, query, args...)
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// close closes every cached statement
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for query, stmt := range c.stmts {
+		errs = append(errs, stmt.Close())
+		delete(c.stmts, query)
+	}
+	return errors.Join(errs...)
+}
+
+// query runs a prepared query on the primary
+func (db *DB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.stmts.query(ctx, query, args...)
+}
+
+// queryRow runs a prepared single-row query on the primary
+func (db *DB) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.stmts.queryRow(ctx, query, args...)
+}
+
+// exec runs a prepared statement on the primary
+func (db *DB) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.stmts.exec(ctx, query, args...)
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// columns is the column list of a model, read from the db tags of its fields. Queries select
+// it in order and scan rows straight into the model:
+//
+//	query := `SELECT ` + userColumns.list() + ` FROM users WHERE id = $1`
+//	user, err := userColumns.scan(r.db.queryRow(ctx, query, id))
+type columns[T any] struct {
+	names  []string
+	fields [][]int
+}
+
+// newColumns lists the tagged fields of T; fields without a db tag, or tagged "-", are skipped
+func newColumns[T any]() columns[T] {
+	var c columns[T]
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for _, field := range reflect.VisibleFields(t) {
+		name := field.Tag.Get("db")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		c.names = append(c.names, name)
+		c.fields = append(c.fields, field.Index)
+	}
+
+	if len(c.names) == 0 {
+		panic(fmt.Sprintf("database: %s has no db tagged fields", t))
+	}
+	return c
+}
+
+// list returns the comma-separated column names, for SELECT and RETURNING clauses
+func (c columns[T]) list() string {
+	return strings.Join(c.names, ", ")
+}
+
+// scan reads one row into a new T
+func (c columns[T]) scan(row rowScanner) (*T, error) {
+	var value T
+
+	v := reflect.ValueOf(&value).Elem()
+	dest := make([]interface{}, len(c.fields))
+	for i, index := range c.fields {
+		dest[i] = v.FieldByIndex(index).Addr().Interface()
+	}
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// scanAll reads every remaining row and closes rows
+func (c columns[T]) scanAll(rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	var values []T
+	for rows.Next() {
+		value, err := c.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, *value)
+	}
+	return values, rows.Err()
+}
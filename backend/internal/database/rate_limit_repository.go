@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RateLimitRepository handles token bucket storage for rate limiting
+type RateLimitRepository struct {
+	db *DB
+}
+
+// NewRateLimitRepository creates a new rate limit repository
+func NewRateLimitRepository(db *DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take refills the bucket for key at ratePerSecond up to capacity and removes one token if
+// available, in a single statement. Buckets use the database clock so replicas agree on time.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, capacity, ratePerSecond float64) (_ bool, _ float64, err error) {
+	defer r.db.observe(ctx, "RateLimitRepository.Take")(&err)
+
+	// refill is the bucket's token count after refilling for the time since its last update
+	const refill = `LEAST($2::float8, rate_limit_buckets.tokens +
+		EXTRACT(EPOCH FROM (now() - rate_limit_buckets.updated_at))::float8 * $3::float8)`
+
+	query := `
+		INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN ` + refill + ` >= 1 THEN ` + refill + ` - 1 ELSE ` + refill + ` END,
+			allowed = ` + refill + ` >= 1,
+			updated_at = GREATEST(rate_limit_buckets.updated_at, now())
+		RETURNING allowed, tokens
+	`
+
+	var allowed bool
+	var tokens float64
+	err = r.db.DB.QueryRowContext(ctx, query, key, capacity, ratePerSecond).Scan(&allowed, &tokens)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return allowed, tokens, nil
+}
+
+// DeleteIdle deletes buckets that have not been used for longer than maxIdle
+func (r *RateLimitRepository) DeleteIdle(ctx context.Context, maxIdle time.Duration) (err error) {
+	defer r.db.observe(ctx, "RateLimitRepository.DeleteIdle")(&err)
+
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < now() - make_interval(secs => $1)`
+
+	if _, err = r.db.DB.ExecContext(ctx, query, maxIdle.Seconds()); err != nil {
+		return fmt.Errorf("failed to delete idle rate limit buckets: %w", err)
+	}
+
+	return nil
+}
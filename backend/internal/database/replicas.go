@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goapi/internal/config"
+	"goapi/internal/consistency"
+	"goapi/internal/metrics"
+	"goapi/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// replica is a read-only copy of the primary database
+type replica struct {
+	name    string
+	db      *sql.DB
+	stmts   *stmtCache
+	cfg     config.DatabaseConfig
+	healthy atomic.Bool
+}
+
+// replicaSet spreads reads round-robin over the healthy replicas and remembers which sessions
+// wrote recently, since their reads must see the write before replication catches up
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	window   time.Duration
+	log      logger.Logger
+	metrics  *metrics.Metrics
+
+	mu        sync.Mutex
+	lastWrite map[string]time.Time
+	lastSweep time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// openReplicas connects to the configured replicas and starts checking their health. Replicas
+// that cannot be reached yet do not fail startup; reads use the primary until they recover.
+func openReplicas(cfg config.DatabaseConfig, log logger.Logger, m *metrics.Metrics, secrets config.SecretProvider) (*replicaSet, error) {
+	configs, err := cfg.ReplicaConfigs()
+	if err != nil || len(configs) == 0 {
+		return nil, err
+	}
+
+	set := &replicaSet{
+		window:    time.Duration(cfg.ReadYourWritesWindow) * time.Second,
+		log:       log,
+		metrics:   m,
+		lastWrite: make(map[string]time.Time),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	for _, rc := range configs {
+		r := &replica{name: net.JoinHostPort(rc.Host, rc.Port), db: sql.OpenDB(newConnector(rc, secrets)), cfg: rc}
+		r.stmts = newStmtCache(r.db)
+		configurePool(r.db, rc)
+		m.RegisterDB(r.db, rc.DBName+"@"+r.name)
+
+		set.replicas = append(set.replicas, r)
+
+		// Replicas start out unhealthy, so only a successful first check is logged by setHealthy
+		err := ping(context.Background(), r.db, rc)
+		if err != nil {
+			log.Warn("Database replica unavailable, reading from primary", "replica", r.name, "error", err)
+		}
+		set.setHealthy(r, err)
+	}
+
+	go set.run(time.Duration(cfg.ReplicaCheckInterval) * time.Second)
+
+	return set, nil
+}
+
+// names lists the replicas for logging
+func (s *replicaSet) names() []string {
+	names := make([]string, len(s.replicas))
+	for i, r := range s.replicas {
+		names[i] = r.name
+	}
+	return names
+}
+
+// pick returns the next healthy replica, or nil when none is healthy
+func (s *replicaSet) pick() *replica {
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// healthyByName returns the named replica if it is healthy
+func (s *replicaSet) healthyByName(name string) *replica {
+	for _, r := range s.replicas {
+		if r.name == name && r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// run checks every replica on each tick until the set is closed
+func (s *replicaSet) run(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for _, r := range s.replicas {
+				s.check(context.Background(), r)
+			}
+		}
+	}
+}
+
+// check pings a replica and records whether it answered
+func (s *replicaSet) check(ctx context.Context, r *replica) {
+	s.setHealthy(r, ping(ctx, r.db, r.cfg))
+}
+
+// setHealthy records the outcome of a check or query, logging when the state changes
+func (s *replicaSet) setHealthy(r *replica, err error) {
+	healthy := err == nil
+	s.metrics.SetDBReplicaHealthy(r.name, healthy)
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		s.log.Info("Database replica available", "replica", r.name)
+	} else {
+		s.log.Warn("Database replica unavailable, reading from primary", "replica", r.name, "error", err)
+	}
+}
+
+// recordWrite remembers that the session wrote now
+func (s *replicaSet) recordWrite(key string) {
+	if key == "" || s.window <= 0 {
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastWrite[key] = now
+
+	// Forget sessions whose window has passed, at most once per window
+	if now.Sub(s.lastSweep) >= s.window {
+		for k, t := range s.lastWrite {
+			if now.Sub(t) >= s.window {
+				delete(s.lastWrite, k)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
+// wroteRecently reports whether the session wrote within the read-your-writes window
+func (s *replicaSet) wroteRecently(key string) bool {
+	if key == "" || s.window <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.lastWrite[key]
+	return ok && time.Since(t) < s.window
+}
+
+// close stops the health checks and closes every replica pool
+func (s *replicaSet) close() error {
+	close(s.stop)
+	<-s.done
+
+	var errs []error
+	for _, r := range s.replicas {
+		errs = append(errs, r.stmts.close(), r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// reader returns the replica reads for ctx should use, or nil when they must use the primary:
+// there are no healthy replicas, ctx requires the primary, or its session wrote recently
+func (db *DB) reader(ctx context.Context) *replica {
+	if db.replicas == nil || consistency.PrimaryRequired(ctx) {
+		return nil
+	}
+	if session, ok := consistency.SessionFromContext(ctx); ok {
+		if session.Wrote() || db.replicas.wroteRecently(session.Key) {
+			return nil
+		}
+	}
+
+	// A pinned request keeps to its first choice while that replica is healthy, falling back
+	// to the primary, which is never behind it
+	if pin, ok := consistency.PinFromContext(ctx); ok {
+		name := pin.Resolve(func() string {
+			if r := db.replicas.pick(); r != nil {
+				return r.name
+			}
+			return ""
+		})
+		return db.replicas.healthyByName(name)
+	}
+	return db.replicas.pick()
+}
+
+// markWrite records a successful write, keeping the session's following reads on the primary
+func (db *DB) markWrite(ctx context.Context) {
+	session, ok := consistency.SessionFromContext(ctx)
+	if !ok {
+		return
+	}
+	session.MarkWrite()
+	if db.replicas != nil {
+		db.replicas.recordWrite(session.Key)
+	}
+}
+
+// queryRead runs a prepared read-only query on a replica when possible. A replica that cannot
+// be reached is marked unhealthy and the query is retried on the primary.
+func (db *DB) queryRead(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if r := db.reader(ctx); r != nil {
+		rows, err := r.stmts.query(ctx, query, args...)
+		if err == nil || !db.replicaUnavailable(ctx, r, err) {
+			return rows, err
+		}
+	}
+	return db.query(ctx, query, args...)
+}
+
+// queryRowRead is queryRead for queries returning at most one row
+func (db *DB) queryRowRead(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if r := db.reader(ctx); r != nil {
+		row := r.stmts.queryRow(ctx, query, args...)
+		if err := row.Err(); err == nil || !db.replicaUnavailable(ctx, r, err) {
+			return row
+		}
+	}
+	return db.queryRow(ctx, query, args...)
+}
+
+// replicaUnavailable reports whether err means the replica could not serve the query, in which
+// case it is marked unhealthy until the next successful check
+func (db *DB) replicaUnavailable(ctx context.Context, r *replica, err error) bool {
+	if ctx.Err() != nil || !isConnectionError(err) {
+		return false
+	}
+	db.replicas.setHealthy(r, err)
+	return true
+}
+
+// isConnectionError reports whether err comes from the connection rather than the query.
+// Postgres errors are query errors except for connection exceptions (class 08) and operator
+// intervention such as a shutdown (class 57).
+func isConnectionError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		class := string(pqErr.Code.Class())
+		return class == "08" || (class == "57" && pqErr.Code.Name() != "query_canceled")
+	}
+	return !errors.Is(err, sql.ErrNoRows) && !strings.HasPrefix(err.Error(), "sql: ")
+}
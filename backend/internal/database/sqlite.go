@@ -0,0 +1,203 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"goapi/internal/config"
+	"goapi/internal/metrics"
+	"goapi/pkg/logger"
+
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteDatabase opens the SQLite database file, creating it and its tables when missing
+func newSQLiteDatabase(cfg config.DatabaseConfig, log logger.Logger, m *metrics.Metrics) (*DB, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(cfg.SQLitePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	configurePool(db, cfg)
+
+	// Every connection to :memory: gets its own empty database, so keep a single one for good
+	if cfg.SQLitePath == ":memory:" {
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxIdleTime(0)
+		db.SetConnMaxLifetime(0)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	log.Info("Database connected", "driver", "sqlite", "path", cfg.SQLitePath)
+
+	if err := createSQLiteTables(db, log); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	m.RegisterDB(db, cfg.SQLitePath)
+
+	return &DB{DB: db, log: log, metrics: m, dialect: sqliteDialect, stmts: newStmtCache(db)}, nil
+}
+
+// sqliteDSN adds the pragmas every connection needs: enforced foreign keys, waiting for locks
+// instead of failing with SQLITE_BUSY, and write-ahead logging so reads do not block writes.
+// Times are written in SQLite's own format rather than time.Time.String, see dbTime.
+func sqliteDSN(path string) string {
+	pragmas := url.Values{"_pragma": {"foreign_keys(1)", "busy_timeout(5000)"}, "_time_format": {"sqlite"}}
+	if path != ":memory:" {
+		pragmas["_pragma"] = append(pragmas["_pragma"], "journal_mode(WAL)")
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return "file:" + path + separator + pragmas.Encode()
+}
+
+// createSQLiteTables creates the same tables as createTables, in SQLite's dialect. updated_at
+// is kept current by triggers, which fire for rows that did not set it themselves.
+func createSQLiteTables(db *sql.DB, log logger.Logger) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS organizations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(100) NOT NULL,
+		slug VARCHAR(63) UNIQUE NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+		updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+	);
+
+	CREATE TRIGGER IF NOT EXISTS update_organizations_updated_at
+		AFTER UPDATE ON organizations
+		FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+		BEGIN
+			UPDATE organizations SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+		END;
+
+	INSERT INTO organizations (name, slug) VALUES ('Default', 'default') ON CONFLICT (slug) DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		email VARCHAR(100) NOT NULL,
+		role VARCHAR(32) NOT NULL DEFAULT 'user',
+		password_hash VARCHAR(255),
+		email_verified_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+		updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_org_email ON users(org_id, email);
+
+	CREATE TRIGGER IF NOT EXISTS update_users_updated_at
+		AFTER UPDATE ON users
+		FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+		BEGIN
+			UPDATE users SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+		END;
+
+	CREATE TABLE IF NOT EXISTS user_mfa (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		enabled_at TIMESTAMP,
+		last_totp_step INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+	CREATE TABLE IF NOT EXISTS consumed_tokens (
+		id VARCHAR(64) PRIMARY KEY,
+		expires_at TIMESTAMP NOT NULL,
+		consumed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS login_throttles (
+		key VARCHAR(255) PRIMARY KEY,
+		failures INTEGER NOT NULL DEFAULT 0,
+		window_started_at TIMESTAMP NOT NULL,
+		last_failure_at TIMESTAMP NOT NULL,
+		locked_until TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type VARCHAR(64) NOT NULL,
+		user_id INTEGER,
+		actor_id INTEGER,
+		ip VARCHAR(64),
+		details TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id);
+
+	CREATE TABLE IF NOT EXISTS invitations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		email VARCHAR(100) NOT NULL,
+		role VARCHAR(32) NOT NULL DEFAULT 'user',
+		token_id VARCHAR(64) UNIQUE NOT NULL,
+		invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		expires_at TIMESTAMP NOT NULL,
+		accepted_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_invitations_org_id ON invitations(org_id);
+
+	CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+		key VARCHAR(255) PRIMARY KEY,
+		tokens DOUBLE PRECISION NOT NULL,
+		allowed BOOLEAN NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	`
+
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	// Columns added to existing tables
+	if err := addSQLiteColumn(db, "user_mfa", "last_totp_step", "INTEGER"); err != nil {
+		return err
+	}
+
+	log.Info("Database tables created")
+	return nil
+}
+
+// addSQLiteColumn adds a column to a table created before it existed. SQLite has no
+// ADD COLUMN IF NOT EXISTS, so the table's columns are checked first.
+func addSQLiteColumn(db *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info($1) WHERE name = $2`, table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
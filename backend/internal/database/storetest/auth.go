@@ -0,0 +1,177 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"goapi/internal/database"
+	"goapi/internal/models"
+)
+
+// AuthRepository checks the single-use token records behind email verification and password
+// resets: each token is consumed once even by concurrent requests, records outlive their tokens
+// whatever zone the expiry is given in, and a consumption whose change fails is rolled back. It
+// also checks that each TOTP time step is accepted once, and none before the last accepted.
+func AuthRepository(t *testing.T, db *database.DB) {
+	ctx := context.Background()
+	authRepo := database.NewAuthRepository(db)
+
+	consumers := []struct {
+		name    string
+		consume func(tokenID string, expiresAt time.Time, user *models.User) error
+	}{
+		{"verify email", func(tokenID string, expiresAt time.Time, user *models.User) error {
+			return authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, user.Email)
+		}},
+		{"reset password", func(tokenID string, expiresAt time.Time, user *models.User) error {
+			return authRepo.ResetPassword(ctx, tokenID, expiresAt, user.ID, "hash")
+		}},
+	}
+
+	for _, c := range consumers {
+		t.Run(c.name+" consumes a token once", func(t *testing.T) {
+			user := newUser(t, db, newOrg(t, db), "once@example.com")
+			tokenID := unique("token")
+			expiresAt := time.Now().Add(time.Hour)
+
+			if err := c.consume(tokenID, expiresAt, user); err != nil {
+				t.Fatalf("first use: %v", err)
+			}
+			expectTokenUsed(t, c.consume(tokenID, expiresAt, user))
+		})
+
+		t.Run(c.name+" consumes a token once when used concurrently", func(t *testing.T) {
+			user := newUser(t, db, newOrg(t, db), "concurrent@example.com")
+			tokenID := unique("token")
+			expiresAt := time.Now().Add(time.Hour)
+
+			errs := concurrently(8, func() error { return c.consume(tokenID, expiresAt, user) })
+
+			consumed := 0
+			for _, err := range errs {
+				if err == nil {
+					consumed++
+					continue
+				}
+				expectTokenUsed(t, err)
+			}
+			if consumed != 1 {
+				t.Fatalf("token consumed %d times, want 1", consumed)
+			}
+		})
+	}
+
+	t.Run("TOTP steps are accepted once and in order", func(t *testing.T) {
+		user := newUser(t, db, newOrg(t, db), "totp@example.com")
+		enableMFA(t, authRepo, user.ID)
+
+		uses := []struct {
+			step int64
+			want bool
+		}{
+			{100, true},
+			{100, false}, // replayed
+			{99, false},  // older than the last accepted
+			{101, true},
+			{103, true}, // steps may be skipped
+			{102, false},
+		}
+
+		for _, u := range uses {
+			used, err := authRepo.UseTOTPStep(ctx, user.ID, u.step)
+			if err != nil {
+				t.Fatalf("UseTOTPStep(%d): %v", u.step, err)
+			}
+			if used != u.want {
+				t.Fatalf("UseTOTPStep(%d) = %v, want %v", u.step, used, u.want)
+			}
+		}
+	})
+
+	t.Run("TOTP steps are accepted once when used concurrently", func(t *testing.T) {
+		user := newUser(t, db, newOrg(t, db), "totp-concurrent@example.com")
+		enableMFA(t, authRepo, user.ID)
+
+		var mu sync.Mutex
+		accepted := 0
+		errs := concurrently(8, func() error {
+			used, err := authRepo.UseTOTPStep(ctx, user.ID, 100)
+			if used {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+			return err
+		})
+
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("UseTOTPStep: %v", err)
+			}
+		}
+		if accepted != 1 {
+			t.Fatalf("step accepted %d times, want 1", accepted)
+		}
+	})
+
+	t.Run("unexpired tokens stay consumed when records are pruned", func(t *testing.T) {
+		user := newUser(t, db, newOrg(t, db), "unexpired@example.com")
+		tokenID := unique("token")
+		expiresAt := time.Now().Add(2 * time.Hour).In(westOfUTC)
+
+		if err := authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, user.Email); err != nil {
+			t.Fatalf("VerifyEmail: %v", err)
+		}
+		// Consuming any token prunes expired records
+		if err := authRepo.VerifyEmail(ctx, unique("token"), time.Now().Add(time.Hour), user.ID, user.Email); err != nil {
+			t.Fatalf("VerifyEmail: %v", err)
+		}
+
+		expectTokenUsed(t, authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, user.Email))
+	})
+
+	t.Run("expired token records are pruned", func(t *testing.T) {
+		user := newUser(t, db, newOrg(t, db), "expired@example.com")
+		tokenID := unique("token")
+		expiresAt := time.Now().Add(-time.Minute)
+
+		if err := authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, user.Email); err != nil {
+			t.Fatalf("VerifyEmail: %v", err)
+		}
+		if err := authRepo.VerifyEmail(ctx, unique("token"), time.Now().Add(time.Hour), user.ID, user.Email); err != nil {
+			t.Fatalf("VerifyEmail: %v", err)
+		}
+
+		// The record is gone; services never get this far with an expired token
+		if err := authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, user.Email); err != nil {
+			t.Fatalf("expired record was kept: %v", err)
+		}
+	})
+
+	t.Run("a token is not consumed when its change fails", func(t *testing.T) {
+		user := newUser(t, db, newOrg(t, db), "rollback@example.com")
+		tokenID := unique("token")
+		expiresAt := time.Now().Add(time.Hour)
+
+		// The address no longer matches, so nothing is verified
+		expectNotFound(t, authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, "old@example.com"))
+
+		if err := authRepo.VerifyEmail(ctx, tokenID, expiresAt, user.ID, user.Email); err != nil {
+			t.Fatalf("token was consumed by the failed attempt: %v", err)
+		}
+	})
+}
+
+// enableMFA confirms an MFA enrollment for the user
+func enableMFA(t *testing.T, authRepo *database.AuthRepository, userID int) {
+	t.Helper()
+	ctx := context.Background()
+	if err := authRepo.SavePendingMFA(ctx, userID, "secret"); err != nil {
+		t.Fatalf("failed to save mfa secret: %v", err)
+	}
+	if err := authRepo.EnableMFA(ctx, userID, nil); err != nil {
+		t.Fatalf("failed to enable mfa: %v", err)
+	}
+}
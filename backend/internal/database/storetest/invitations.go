@@ -0,0 +1,98 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goapi/internal/database"
+	"goapi/internal/models"
+)
+
+// InvitationRepository checks that invitations expire at their expiry whatever zone it is
+// given in, that a pending invitation is claimed by at most one verified user, and that expired,
+// revoked and replaced invitations cannot be claimed.
+func InvitationRepository(t *testing.T, db *database.DB) {
+	ctx := context.Background()
+	invitations := database.NewInvitationRepository(db)
+	users := database.NewUserRepository(db)
+
+	invite := func(t *testing.T, orgID int, email string, expiresAt time.Time) *models.Invitation {
+		t.Helper()
+		inv, err := invitations.Create(ctx, models.Invitation{OrgID: orgID, Email: email, Role: "user", TokenID: unique("invite"), ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("failed to create invitation: %v", err)
+		}
+		return inv
+	}
+
+	accept := func(inv *models.Invitation) (*models.User, error) {
+		return users.CreateVerified(ctx, inv.OrgID, models.CreateUserRequest{Name: "Invited", Email: inv.Email, Role: inv.Role}, "", invitations.Claim(inv.ID))
+	}
+
+	t.Run("pending invitations are the unexpired ones", func(t *testing.T) {
+		orgID := newOrg(t, db)
+		pending := invite(t, orgID, "pending@example.com", time.Now().Add(2*time.Hour).In(westOfUTC))
+		invite(t, orgID, "expired@example.com", time.Now().Add(-time.Minute))
+
+		list, err := invitations.GetPending(ctx, orgID)
+		if err != nil {
+			t.Fatalf("GetPending: %v", err)
+		}
+		if len(list) != 1 || list[0].ID != pending.ID {
+			t.Fatalf("GetPending returned %+v, want only invitation %d", list, pending.ID)
+		}
+		if !list[0].ExpiresAt.Equal(pending.ExpiresAt) {
+			t.Fatalf("expires_at %v was stored as %v", pending.ExpiresAt, list[0].ExpiresAt)
+		}
+	})
+
+	t.Run("accepting creates a verified user once", func(t *testing.T) {
+		orgID := newOrg(t, db)
+		inv := invite(t, orgID, "accept@example.com", time.Now().Add(2*time.Hour).In(westOfUTC))
+
+		user, err := accept(inv)
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		if user.OrgID != orgID || user.Email != inv.Email || user.EmailVerifiedAt == nil {
+			t.Fatalf("unexpected user %+v", user)
+		}
+
+		_, err = accept(inv)
+		expectNotFound(t, err)
+	})
+
+	t.Run("only pending invitations can be accepted", func(t *testing.T) {
+		orgID := newOrg(t, db)
+		expired := invite(t, orgID, "expired@example.com", time.Now().Add(-time.Minute))
+		revoked := invite(t, orgID, "revoked@example.com", time.Now().Add(time.Hour))
+		if err := invitations.Revoke(ctx, orgID, revoked.ID); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+		replaced := invite(t, orgID, "replaced@example.com", time.Now().Add(time.Hour))
+		invite(t, orgID, "replaced@example.com", time.Now().Add(time.Hour))
+
+		for name, inv := range map[string]*models.Invitation{"expired": expired, "revoked": revoked, "replaced": replaced} {
+			if _, err := accept(inv); err == nil {
+				t.Fatalf("%s invitation was accepted", name)
+			} else {
+				expectNotFound(t, err)
+			}
+		}
+	})
+
+	t.Run("a taken email leaves the invitation pending", func(t *testing.T) {
+		orgID := newOrg(t, db)
+		newUser(t, db, orgID, "taken@example.com")
+		inv := invite(t, orgID, "taken@example.com", time.Now().Add(time.Hour))
+
+		_, err := accept(inv)
+		expectMessage(t, err, "email already exists")
+
+		stored, err := invitations.GetByTokenID(ctx, inv.TokenID)
+		if err != nil || stored.AcceptedAt != nil {
+			t.Fatalf("invitation = %+v, %v; want it still pending", stored, err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package storetest
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"goapi/internal/database"
+)
+
+// LockoutRepository checks the login throttle counts LoginGuard judges attempts by: concurrent
+// reservations each see a count of their own, released reservations are given back, a new
+// window restarts the count and a locked key is left as it is until its lock expires.
+func LockoutRepository(t *testing.T, db *database.DB) {
+	ctx := context.Background()
+	lockouts := database.NewLockoutRepository(db)
+
+	reserve := func(t *testing.T, key string, now time.Time) int {
+		t.Helper()
+		throttle, err := lockouts.Reserve(ctx, key, now, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		return throttle.Failures
+	}
+
+	t.Run("concurrent reservations each see their own count", func(t *testing.T) {
+		key := unique("key")
+		now := time.Now()
+
+		counts := make(chan int, 8)
+		errs := concurrently(cap(counts), func() error {
+			throttle, err := lockouts.Reserve(ctx, key, now, now.Add(-time.Hour))
+			if err == nil {
+				counts <- throttle.Failures
+			}
+			return err
+		})
+		close(counts)
+
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("Reserve: %v", err)
+			}
+		}
+
+		var got []int
+		for count := range counts {
+			got = append(got, count)
+		}
+		sort.Ints(got)
+		for i, count := range got {
+			if count != i+1 {
+				t.Fatalf("reservations saw counts %v, want 1 to %d once each", got, len(errs))
+			}
+		}
+	})
+
+	// Each case runs its steps in order against a fresh key. Reservations expect the count they
+	// return; the other steps expect nothing.
+	type step struct {
+		do   string
+		want int
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"released reservations are given back", []step{{"reserve", 1}, {"reserve", 2}, {"release", 0}, {"reserve", 2}}},
+		{"a new window restarts the count", []step{{"reserve in old window", 1}, {"reserve in old window", 2}, {"reserve", 1}}},
+		{"a locked key is left as it is", []step{{"reserve", 1}, {"lock", 0}, {"reserve", 0}, {"reserve", 0}}},
+		{"an expired lock counts again", []step{{"reserve", 1}, {"lock expired", 0}, {"reserve", 1}}},
+		{"a cleared key starts over", []step{{"reserve", 1}, {"reserve", 2}, {"clear", 0}, {"reserve", 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := unique("key")
+			now := time.Now()
+
+			for _, s := range tt.steps {
+				var err error
+				switch s.do {
+				case "reserve":
+					if got := reserve(t, key, now); got != s.want {
+						t.Fatalf("%s: count %d, want %d", s.do, got, s.want)
+					}
+				case "reserve in old window":
+					if got := reserve(t, key, now.Add(-2*time.Hour)); got != s.want {
+						t.Fatalf("%s: count %d, want %d", s.do, got, s.want)
+					}
+				case "release":
+					err = lockouts.Release(ctx, key)
+				case "lock":
+					err = lockouts.Lock(ctx, key, now.Add(time.Hour).In(westOfUTC))
+				case "lock expired":
+					err = lockouts.Lock(ctx, key, now.Add(-time.Minute))
+				case "clear":
+					err = lockouts.Clear(ctx, key)
+				default:
+					t.Fatalf("unknown step %q", s.do)
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", s.do, err)
+				}
+			}
+		})
+	}
+
+	t.Run("failures of a cleared key are not recorded", func(t *testing.T) {
+		key := unique("key")
+		reserve(t, key, time.Now())
+		if err := lockouts.Clear(ctx, key); err != nil {
+			t.Fatalf("Clear: %v", err)
+		}
+
+		throttle, err := lockouts.RecordFailure(ctx, key, time.Now())
+		if err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+		if throttle != nil {
+			t.Fatalf("RecordFailure recreated the key: %+v", throttle)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapi/internal/database"
+	"goapi/internal/models"
+)
+
+// UserRepository checks the behaviour services rely on from database.UserRepository: results
+// are scoped to an organization, emails are unique per organization, expected failures carry
+// the messages handlers map to responses, and updated_at moves forward on every update.
+func UserRepository(t *testing.T, db *database.DB) {
+	ctx := context.Background()
+	users := database.NewUserRepository(db)
+	orgs := database.NewOrganizationRepository(db)
+
+	newOrg := func(t *testing.T) int {
+		t.Helper()
+		slug := unique("org")
+		org, err := orgs.Create(ctx, models.CreateOrganizationRequest{Name: slug, Slug: slug})
+		if err != nil {
+			t.Fatalf("failed to create organization: %v", err)
+		}
+		return org.ID
+	}
+
+	create := func(t *testing.T, orgID int, email string) *models.User {
+		t.Helper()
+		user, err := users.Create(ctx, orgID, models.CreateUserRequest{Name: "Test User", Email: email, Role: "user"}, "")
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		return user
+	}
+
+	t.Run("create returns the stored user", func(t *testing.T) {
+		orgID := newOrg(t)
+		user := create(t, orgID, "create@example.com")
+
+		if user.ID == 0 || user.OrgID != orgID || user.Email != "create@example.com" || user.Role != "user" {
+			t.Fatalf("unexpected user %+v", user)
+		}
+		if user.CreatedAt.IsZero() || user.UpdatedAt.IsZero() {
+			t.Fatalf("timestamps not set: %+v", user)
+		}
+		if user.EmailVerifiedAt != nil {
+			t.Fatalf("new user should not be verified")
+		}
+	})
+
+	t.Run("emails are unique per organization", func(t *testing.T) {
+		orgID, otherOrgID := newOrg(t), newOrg(t)
+		create(t, orgID, "dup@example.com")
+
+		_, err := users.Create(ctx, orgID, models.CreateUserRequest{Name: "Again", Email: "dup@example.com", Role: "user"}, "")
+		expectMessage(t, err, "email already exists")
+
+		create(t, otherOrgID, "dup@example.com")
+
+		other := create(t, orgID, "other@example.com")
+		_, err = users.Update(ctx, orgID, other.ID, models.UpdateUserRequest{Name: "Other", Email: "dup@example.com"})
+		expectMessage(t, err, "email already exists")
+	})
+
+	t.Run("lookups are scoped to the organization", func(t *testing.T) {
+		orgID, otherOrgID := newOrg(t), newOrg(t)
+		user := create(t, orgID, "scoped@example.com")
+
+		got, err := users.GetByID(ctx, orgID, user.ID)
+		if err != nil || got.Email != user.Email {
+			t.Fatalf("GetByID = %+v, %v", got, err)
+		}
+		got, err = users.GetByEmail(ctx, orgID, user.Email)
+		if err != nil || got.ID != user.ID {
+			t.Fatalf("GetByEmail = %+v, %v", got, err)
+		}
+
+		_, err = users.GetByID(ctx, otherOrgID, user.ID)
+		expectNotFound(t, err)
+		_, err = users.GetByEmail(ctx, otherOrgID, user.Email)
+		expectNotFound(t, err)
+	})
+
+	t.Run("get all lists the organization's users newest first", func(t *testing.T) {
+		orgID, otherOrgID := newOrg(t), newOrg(t)
+		first := create(t, orgID, "first@example.com")
+		time.Sleep(10 * time.Millisecond)
+		second := create(t, orgID, "second@example.com")
+		create(t, otherOrgID, "elsewhere@example.com")
+
+		list, err := users.GetAll(ctx, orgID)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if len(list) != 2 || list[0].ID != second.ID || list[1].ID != first.ID {
+			t.Fatalf("GetAll returned %+v", list)
+		}
+	})
+
+	t.Run("version changes with every create, update and delete", func(t *testing.T) {
+		orgID := newOrg(t)
+		version := func() models.UserListVersion {
+			t.Helper()
+			v, err := users.Version(ctx, orgID)
+			if err != nil {
+				t.Fatalf("Version: %v", err)
+			}
+			return v
+		}
+
+		empty := version()
+		if empty.Count != 0 || empty.LastModified != nil {
+			t.Fatalf("empty list has version %+v", empty)
+		}
+
+		user := create(t, orgID, "version@example.com")
+		created := version()
+		if created.Count != 1 || created.LastModified == nil || !created.LastModified.Equal(user.UpdatedAt) {
+			t.Fatalf("after create got version %+v, user updated at %v", created, user.UpdatedAt)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		if _, err := users.Update(ctx, orgID, user.ID, models.UpdateUserRequest{Name: "Renamed", Email: user.Email}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		updated := version()
+		if updated.Count != 1 || !updated.LastModified.After(*created.LastModified) {
+			t.Fatalf("after update got version %+v, was %+v", updated, created)
+		}
+
+		if err := users.Delete(ctx, orgID, user.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if deleted := version(); deleted.Count != 0 {
+			t.Fatalf("after delete got version %+v", deleted)
+		}
+	})
+
+	t.Run("stream yields get all's users and stops at the callback's error", func(t *testing.T) {
+		orgID := newOrg(t)
+		create(t, orgID, "one@example.com")
+		create(t, orgID, "two@example.com")
+
+		list, err := users.GetAll(ctx, orgID)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+
+		var streamed []int
+		err = users.Stream(ctx, orgID, func(user *models.User) error {
+			streamed = append(streamed, user.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		if len(streamed) != len(list) || streamed[0] != list[0].ID || streamed[1] != list[1].ID {
+			t.Fatalf("Stream yielded %v, GetAll returned %+v", streamed, list)
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err = users.Stream(ctx, orgID, func(*models.User) error {
+			calls++
+			return stop
+		})
+		if err != stop || calls != 1 {
+			t.Fatalf("Stream returned %v after %d calls, want the callback's error after 1", err, calls)
+		}
+	})
+
+	t.Run("update advances updated_at and resets verification on email change", func(t *testing.T) {
+		orgID := newOrg(t)
+		user := create(t, orgID, "before@example.com")
+		if err := users.MarkEmailVerified(ctx, orgID, user.ID); err != nil {
+			t.Fatalf("MarkEmailVerified: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		renamed, err := users.Update(ctx, orgID, user.ID, models.UpdateUserRequest{Name: "Renamed", Email: "before@example.com"})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if renamed.Name != "Renamed" || renamed.EmailVerifiedAt == nil {
+			t.Fatalf("name change should keep verification: %+v", renamed)
+		}
+		if !renamed.UpdatedAt.After(user.UpdatedAt) || !renamed.CreatedAt.Equal(user.CreatedAt) {
+			t.Fatalf("updated_at %v should advance past %v, created_at stay", renamed.UpdatedAt, user.UpdatedAt)
+		}
+
+		moved, err := users.Update(ctx, orgID, user.ID, models.UpdateUserRequest{Name: "Renamed", Email: "after@example.com"})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if moved.EmailVerifiedAt != nil {
+			t.Fatalf("email change should reset verification")
+		}
+
+		stored, err := users.GetByID(ctx, orgID, user.ID)
+		if err != nil || !stored.UpdatedAt.Equal(moved.UpdatedAt) {
+			t.Fatalf("stored updated_at %v, returned %v (%v)", stored.UpdatedAt, moved.UpdatedAt, err)
+		}
+	})
+
+	t.Run("other updates advance updated_at", func(t *testing.T) {
+		orgID := newOrg(t)
+		user := create(t, orgID, "verify@example.com")
+		time.Sleep(10 * time.Millisecond)
+
+		if err := users.MarkEmailVerified(ctx, orgID, user.ID); err != nil {
+			t.Fatalf("MarkEmailVerified: %v", err)
+		}
+		verified, err := users.GetByID(ctx, orgID, user.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if verified.EmailVerifiedAt == nil || !verified.UpdatedAt.After(user.UpdatedAt) {
+			t.Fatalf("verification not recorded: %+v", verified)
+		}
+	})
+
+	t.Run("missing users are reported as not found", func(t *testing.T) {
+		orgID := newOrg(t)
+
+		_, err := users.Update(ctx, orgID, 0, models.UpdateUserRequest{Name: "Nobody", Email: "nobody@example.com"})
+		expectNotFound(t, err)
+		expectNotFound(t, users.MarkEmailVerified(ctx, orgID, 0))
+		expectNotFound(t, users.Delete(ctx, orgID, 0))
+	})
+
+	t.Run("delete removes the user", func(t *testing.T) {
+		orgID := newOrg(t)
+		user := create(t, orgID, "delete@example.com")
+
+		if err := users.Delete(ctx, orgID, user.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		_, err := users.GetByID(ctx, orgID, user.ID)
+		expectNotFound(t, err)
+		expectNotFound(t, users.Delete(ctx, orgID, user.ID))
+	})
+}
+
+// expectMessage fails unless err has exactly the message callers match on
+func expectMessage(t *testing.T, err error, message string) {
+	t.Helper()
+	if err == nil || err.Error() != message {
+		t.Fatalf("got error %v, want %q", err, message)
+	}
+}
+
+// expectNotFound fails unless err reports a missing row
+func expectNotFound(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("got error %v, want a not found error", err)
+	}
+}
+
+// expectTokenUsed fails unless err reports an already consumed token
+func expectTokenUsed(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, database.ErrTokenUsed) {
+		t.Fatalf("got error %v, want a token already used error", err)
+	}
+}
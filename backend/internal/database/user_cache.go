@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"goapi/internal/cache"
+	"goapi/internal/config"
+	"goapi/internal/consistency"
+	"goapi/internal/metrics"
+	"goapi/internal/models"
+	"goapi/pkg/logger"
+
+	"github.com/lib/pq"
+	"golang.org/x/sync/singleflight"
+)
+
+// userCacheChannel is the LISTEN/NOTIFY channel carrying the IDs of changed users
+const userCacheChannel = "user_cache_invalidation"
+
+// emailKey identifies a user by email within an organization
+type emailKey struct {
+	orgID int
+	email string
+}
+
+// CachedUserRepository decorates a UserRepository with an in-process read-through cache of
+// users by ID and by email. Concurrent misses for the same user share one query, and every
+// write to users through the database, including those of other repositories, invalidates
+// the affected entries. With notify enabled, invalidations are also sent to the other
+// instances over PostgreSQL LISTEN/NOTIFY.
+type CachedUserRepository struct {
+	*UserRepository
+
+	db      *DB
+	users   *cache.LRU[int, *models.User]
+	emails  *cache.LRU[emailKey, int]
+	group   singleflight.Group
+	metrics *metrics.Metrics
+	log     logger.Logger
+
+	// generation changes on every invalidation so loads that raced one are not cached
+	generation atomic.Uint64
+	// tombstoneTTL is how long reads of an invalidated user go to the primary, so a lagging
+	// replica cannot put the old row back in the cache
+	tombstoneTTL time.Duration
+
+	notify   bool
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// NewCachedUserRepository wraps repo with a cache and starts listening for invalidations from
+// other instances when cfg.Notify is set
+func NewCachedUserRepository(repo *UserRepository, cfg config.UserCacheConfig, log logger.Logger, m *metrics.Metrics) (*CachedUserRepository, error) {
+	c := &CachedUserRepository{
+		UserRepository: repo,
+		db:             repo.db,
+		users:          cache.NewLRU[int, *models.User](cfg.Size, time.Duration(cfg.TTL)*time.Second),
+		emails:         cache.NewLRU[emailKey, int](cfg.Size, time.Duration(cfg.TTL)*time.Second),
+		metrics:        m,
+		log:            log,
+		notify:         cfg.Notify,
+	}
+	if repo.db.replicas != nil {
+		c.tombstoneTTL = repo.db.replicas.window
+	}
+
+	if c.notify {
+		if err := c.listen(); err != nil {
+			return nil, err
+		}
+	}
+
+	repo.db.onUsersChanged = c.publish
+	return c, nil
+}
+
+// GetByID returns the cached user, loading it on a miss
+func (c *CachedUserRepository) GetByID(ctx context.Context, orgID, id int) (*models.User, error) {
+	user, cached := c.users.Get(id)
+	if user != nil && user.OrgID == orgID {
+		c.metrics.ObserveCacheLookup("users", true)
+		return copyUser(user), nil
+	}
+	c.metrics.ObserveCacheLookup("users", false)
+
+	// A cached nil is a tombstone left by an invalidation
+	key := fmt.Sprintf("id:%d:%d", orgID, id)
+	return c.load(ctx, key, cached && user == nil, func(ctx context.Context) (*models.User, error) {
+		return c.UserRepository.GetByID(ctx, orgID, id)
+	})
+}
+
+// GetByEmail returns the cached user, loading it on a miss. Emails map to user IDs, so a user
+// is cached once and invalidated by ID; a mapping left stale by an email change is detected
+// because the cached user no longer has the address.
+func (c *CachedUserRepository) GetByEmail(ctx context.Context, orgID int, email string) (*models.User, error) {
+	var tombstone bool
+	if id, ok := c.emails.Get(emailKey{orgID, email}); ok {
+		user, cached := c.users.Get(id)
+		if user != nil && user.OrgID == orgID && user.Email == email {
+			c.metrics.ObserveCacheLookup("users", true)
+			return copyUser(user), nil
+		}
+		tombstone = cached && user == nil
+	}
+	c.metrics.ObserveCacheLookup("users", false)
+
+	key := fmt.Sprintf("email:%d:%s", orgID, email)
+	return c.load(ctx, key, tombstone, func(ctx context.Context) (*models.User, error) {
+		return c.UserRepository.GetByEmail(ctx, orgID, email)
+	})
+}
+
+// load runs fetch once for all concurrent callers asking for key and caches the user found.
+// The query is detached from the first caller's cancellation since the others wait on it.
+func (c *CachedUserRepository) load(ctx context.Context, key string, primary bool, fetch func(context.Context) (*models.User, error)) (*models.User, error) {
+	result, err, _ := c.group.Do(key, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		if primary {
+			ctx = consistency.WithPrimary(ctx)
+		}
+
+		generation := c.generation.Load()
+		user, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.generation.Load() == generation {
+			c.store(user)
+		}
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyUser(result.(*models.User)), nil
+}
+
+// store caches user by ID and by email
+func (c *CachedUserRepository) store(user *models.User) {
+	if c.users.Set(user.ID, user) {
+		c.metrics.ObserveCacheEviction("users")
+	}
+	if c.emails.Set(emailKey{user.OrgID, user.Email}, user.ID) {
+		c.metrics.ObserveCacheEviction("users")
+	}
+}
+
+// invalidate drops the user with the given ID, or every user for ID 0
+func (c *CachedUserRepository) invalidate(id int) {
+	c.generation.Add(1)
+
+	switch {
+	case id == 0:
+		c.users.Purge()
+		c.emails.Purge()
+	case c.tombstoneTTL > 0:
+		c.users.SetTTL(id, nil, c.tombstoneTTL)
+	default:
+		c.users.Remove(id)
+	}
+}
+
+// publish invalidates a changed user here and, with notify enabled, on every other instance.
+// A failed notification is logged: the other instances catch up when their entries expire.
+func (c *CachedUserRepository) publish(ctx context.Context, id int) {
+	c.invalidate(id)
+	if !c.notify {
+		return
+	}
+
+	// The write has happened, so notify even if the request is cancelled meanwhile
+	ctx = context.WithoutCancel(ctx)
+	if _, err := c.db.exec(ctx, `SELECT pg_notify($1, $2)`, userCacheChannel, strconv.Itoa(id)); err != nil {
+		c.log.WithContext(ctx).Error("Failed to notify user cache invalidation", "user_id", id, "error", err)
+	}
+}
+
+// listen subscribes to invalidations from other instances. Notifications sent while the
+// listener is reconnecting are lost, so the whole cache is dropped after a reconnect.
+func (c *CachedUserRepository) listen() error {
+	if c.db.connector == nil {
+		return fmt.Errorf("user cache notifications require the postgres driver")
+	}
+
+	dsn, err := c.db.connector.url(context.Background())
+	if err != nil {
+		return err
+	}
+
+	c.listener = pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			c.log.Warn("User cache listener disconnected", "error", err)
+		case pq.ListenerEventReconnected:
+			c.log.Info("User cache listener reconnected")
+			c.invalidate(0)
+		}
+	})
+	if err := c.listener.Listen(userCacheChannel); err != nil {
+		c.listener.Close()
+		return fmt.Errorf("failed to listen for user cache invalidations: %w", err)
+	}
+
+	c.done = make(chan struct{})
+	go c.receive()
+	return nil
+}
+
+// receive applies invalidations until the listener is closed
+func (c *CachedUserRepository) receive() {
+	defer close(c.done)
+
+	for notification := range c.listener.NotificationChannel() {
+		// A nil notification follows a reconnect, handled by the event callback
+		if notification == nil {
+			continue
+		}
+
+		id, err := strconv.Atoi(notification.Extra)
+		if err != nil {
+			c.log.Warn("Ignoring invalid user cache invalidation", "payload", notification.Extra)
+			continue
+		}
+		c.invalidate(id)
+	}
+}
+
+// Close stops listening for invalidations from other instances
+func (c *CachedUserRepository) Close() error {
+	if c.listener == nil {
+		return nil
+	}
+
+	err := c.listener.Close()
+	<-c.done
+	return err
+}
+
+// copyUser returns a copy of a cached user that callers may modify
+func copyUser(user *models.User) *models.User {
+	copied := *user
+	return &copied
+}
@@ -1,89 +1,195 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"goapi/internal/models"
 )
 
+// userColumns are the columns of models.User, in the order they are selected and scanned
+var userColumns = newColumns[models.User]()
+
+// User queries, prepared on first use. The update query depends on the dialect, see NewUserRepository.
+var (
+	getUsersQuery = `
+		SELECT ` + userColumns.list() + `
+		FROM users
+		WHERE org_id = $1
+		ORDER BY created_at DESC
+	`
+
+	getUsersVersionQuery = `
+		SELECT counted.total, latest.updated_at
+		FROM (SELECT COUNT(*) AS total FROM users WHERE org_id = $1) counted
+		LEFT JOIN (
+			SELECT updated_at FROM users WHERE org_id = $1 ORDER BY updated_at DESC LIMIT 1
+		) latest ON 1 = 1
+	`
+
+	getUserByIDQuery = `
+		SELECT ` + userColumns.list() + `
+		FROM users
+		WHERE id = $1 AND org_id = $2
+	`
+
+	getUserByEmailQuery = `
+		SELECT ` + userColumns.list() + `
+		FROM users
+		WHERE email = $1 AND org_id = $2
+	`
+
+	createUserQuery = `
+		INSERT INTO users (org_id, name, email, role, password_hash)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING ` + userColumns.list()
+
+	// createVerifiedUserQuery creates a user whose email is already known to be theirs
+	createVerifiedUserQuery = `
+		INSERT INTO users (org_id, name, email, role, password_hash, email_verified_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), CURRENT_TIMESTAMP)
+		RETURNING ` + userColumns.list()
+
+	deleteUserQuery = `DELETE FROM users WHERE id = $1 AND org_id = $2`
+
+	markEmailVerifiedQuery = `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND org_id = $2
+	`
+)
+
+// Claim is made by CreateVerified in the transaction that creates a user. It takes whatever
+// entitles the user to exist, such as an invitation, and aborts the creation by failing.
+type Claim func(ctx context.Context, tx *sql.Tx) error
+
+// errStopStream ends a stream early when its callback fails. It has no cause, so the callback's
+// error is not recorded as a failed database operation.
+var errStopStream = errors.New("stream stopped by callback")
+
 // UserRepository handles user-related database operations
 type UserRepository struct {
 	db *DB
+
+	updateQuery string
 }
 
 // NewUserRepository creates a new user repository
 func NewUserRepository(db *DB) *UserRepository {
-	return &UserRepository{db: db}
+	// SQLite's updated_at trigger runs after the update, so set it here for RETURNING to see it
+	updateQuery := `
+		UPDATE users
+		SET name = $1, email = $2,
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
+			updated_at = ` + db.dialect.now + `
+		WHERE id = $3 AND org_id = $4
+		RETURNING ` + userColumns.list()
+
+	return &UserRepository{db: db, updateQuery: updateQuery}
 }
 
-// GetAll retrieves all users from the database
-func (r *UserRepository) GetAll() ([]models.User, error) {
-	query := `
-		SELECT id, name, email, created_at, updated_at 
-		FROM users 
-		ORDER BY created_at DESC
-	`
-	
-	rows, err := r.db.DB.Query(query)
+// GetAll retrieves all users in an organization
+func (r *UserRepository) GetAll(ctx context.Context, orgID int) (_ []models.User, err error) {
+	defer r.db.observe(ctx, "UserRepository.GetAll")(&err)
+
+	rows, err := r.db.queryRead(ctx, getUsersQuery, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query users: %w", err)
 	}
+
+	users, err := userColumns.scanAll(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Version returns the current version of an organization's user list without reading it
+func (r *UserRepository) Version(ctx context.Context, orgID int) (_ models.UserListVersion, err error) {
+	defer r.db.observe(ctx, "UserRepository.Version")(&err)
+
+	var version models.UserListVersion
+	if err := r.db.queryRowRead(ctx, getUsersVersionQuery, orgID).Scan(&version.Count, &version.LastModified); err != nil {
+		return models.UserListVersion{}, fmt.Errorf("failed to summarize users: %w", err)
+	}
+
+	return version, nil
+}
+
+// Stream calls fn with each user in an organization, in GetAll's order, reading rows as fn
+// consumes them instead of loading the whole list. It stops at the first error fn returns and
+// returns that error; when ctx is cancelled it returns ctx.Err().
+func (r *UserRepository) Stream(ctx context.Context, orgID int, fn func(*models.User) error) error {
+	var fnErr error
+	err := r.stream(ctx, orgID, func(user *models.User) error {
+		if err := fn(user); err != nil {
+			fnErr = err
+			return errStopStream
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// stream is Stream with fn's errors replaced by errStopStream
+func (r *UserRepository) stream(ctx context.Context, orgID int, fn func(*models.User) error) (err error) {
+	defer r.db.observe(ctx, "UserRepository.Stream")(&err)
+
+	rows, err := r.db.queryRead(ctx, getUsersQuery, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to query users: %w", err)
+	}
 	defer rows.Close()
 
-	var users []models.User
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+		var user *models.User
+		if user, err = userColumns.scan(rows); err != nil {
+			break
+		}
+		if err := fn(user); err != nil {
+			return err
 		}
-		users = append(users, user)
 	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating users: %w", err)
+	if err == nil {
+		err = rows.Err()
 	}
 
-	return users, nil
+	if err != nil {
+		// A client that went away is not a database failure
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to read users: %w", err)
+	}
+	return nil
 }
 
-// GetByID retrieves a user by ID
-func (r *UserRepository) GetByID(id int) (*models.User, error) {
-	query := `
-		SELECT id, name, email, created_at, updated_at 
-		FROM users 
-		WHERE id = $1
-	`
-	
-	var user models.User
-	err := r.db.DB.QueryRow(query, id).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+// GetByID retrieves a user by ID within an organization
+func (r *UserRepository) GetByID(ctx context.Context, orgID, id int) (_ *models.User, err error) {
+	defer r.db.observe(ctx, "UserRepository.GetByID")(&err)
+
+	user, err := userColumns.scan(r.db.queryRowRead(ctx, getUserByIDQuery, id, orgID))
 	if err != nil {
 		if IsNoRowsError(err) {
-			return nil, fmt.Errorf("user with ID %d not found", id)
+			return nil, fmt.Errorf("user with ID %d %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
+// Create creates a new user in an organization with an optional password hash
+func (r *UserRepository) Create(ctx context.Context, orgID int, req models.CreateUserRequest, passwordHash string) (_ *models.User, err error) {
+	defer r.db.observe(ctx, "UserRepository.Create")(&err)
 
-// Create creates a new user
-func (r *UserRepository) Create(req models.CreateUserRequest) (*models.User, error) {
-	query := `
-		INSERT INTO users (name, email) 
-		VALUES ($1, $2) 
-		RETURNING id, name, email, created_at, updated_at
-	`
-	
-	var user models.User
-	err := r.db.DB.QueryRow(query, req.Name, req.Email).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+	user, err := userColumns.scan(r.db.queryRow(ctx, createUserQuery, orgID, req.Name, req.Email, req.Role, passwordHash))
 	if err != nil {
 		if IsUniqueConstraintError(err) {
 			return nil, fmt.Errorf("email already exists")
@@ -91,27 +197,50 @@ func (r *UserRepository) Create(req models.CreateUserRequest) (*models.User, err
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return &user, nil
+	r.db.markWrite(ctx)
+	return user, nil
 }
 
+// CreateVerified creates a user whose email address has been proven, in one transaction with
+// claim: the user is created only if the claim succeeds, and the claim stands only if the user
+// is created. Errors from claim are returned as they are.
+func (r *UserRepository) CreateVerified(ctx context.Context, orgID int, req models.CreateUserRequest, passwordHash string, claim Claim) (_ *models.User, err error) {
+	defer r.db.observe(ctx, "UserRepository.CreateVerified")(&err)
 
-// Update updates an existing user
-func (r *UserRepository) Update(id int, req models.UpdateUserRequest) (*models.User, error) {
-	query := `
-		UPDATE users 
-		SET name = $1, email = $2 
-		WHERE id = $3 
-		RETURNING id, name, email, created_at, updated_at
-	`
-	
-	var user models.User
-	err := r.db.DB.QueryRow(query, req.Name, req.Email, id).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := claim(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	user, err := userColumns.scan(tx.QueryRowContext(ctx, createVerifiedUserQuery, orgID, req.Name, req.Email, req.Role, passwordHash))
+	if err != nil {
+		if IsUniqueConstraintError(err) {
+			return nil, fmt.Errorf("email already exists")
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.db.markWrite(ctx)
+	return user, nil
+}
+
+// Update updates an existing user within an organization
+func (r *UserRepository) Update(ctx context.Context, orgID, id int, req models.UpdateUserRequest) (_ *models.User, err error) {
+	defer r.db.observe(ctx, "UserRepository.Update")(&err)
+
+	user, err := userColumns.scan(r.db.queryRow(ctx, r.updateQuery, req.Name, req.Email, id, orgID))
 	if err != nil {
 		if IsNoRowsError(err) {
-			return nil, fmt.Errorf("user with ID %d not found", id)
+			return nil, fmt.Errorf("user with ID %d %w", id, ErrNotFound)
 		}
 		if IsUniqueConstraintError(err) {
 			return nil, fmt.Errorf("email already exists")
@@ -119,15 +248,16 @@ func (r *UserRepository) Update(id int, req models.UpdateUserRequest) (*models.U
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
-	return &user, nil
+	r.db.markWrite(ctx)
+	r.db.usersChanged(ctx, id)
+	return user, nil
 }
 
+// Delete deletes a user by ID within an organization
+func (r *UserRepository) Delete(ctx context.Context, orgID, id int) (err error) {
+	defer r.db.observe(ctx, "UserRepository.Delete")(&err)
 
-// Delete deletes a user by ID
-func (r *UserRepository) Delete(id int) error {
-	query := `DELETE FROM users WHERE id = $1`
-	
-	result, err := r.db.DB.Exec(query, id)
+	result, err := r.db.exec(ctx, deleteUserQuery, id, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -138,33 +268,48 @@ func (r *UserRepository) Delete(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user with ID %d not found", id)
+		return fmt.Errorf("user with ID %d %w", id, ErrNotFound)
 	}
 
+	r.db.markWrite(ctx)
+	r.db.usersChanged(ctx, id)
 	return nil
 }
 
-// GetByEmail retrieves a user by email
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
-	query := `
-		SELECT id, name, email, created_at, updated_at 
-		FROM users 
-		WHERE email = $1
-	`
-	
-	var user models.User
-	err := r.db.DB.QueryRow(query, email).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+// GetByEmail retrieves a user by email within an organization
+func (r *UserRepository) GetByEmail(ctx context.Context, orgID int, email string) (_ *models.User, err error) {
+	defer r.db.observe(ctx, "UserRepository.GetByEmail")(&err)
+
+	user, err := userColumns.scan(r.db.queryRowRead(ctx, getUserByEmailQuery, email, orgID))
 	if err != nil {
 		if IsNoRowsError(err) {
-			return nil, fmt.Errorf("user with email %s not found", email)
+			return nil, fmt.Errorf("user with email %s %w", email, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
+// MarkEmailVerified marks a user's current email address as verified
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, orgID, id int) (err error) {
+	defer r.db.observe(ctx, "UserRepository.MarkEmailVerified")(&err)
+
+	result, err := r.db.exec(ctx, markEmailVerifiedQuery, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d %w", id, ErrNotFound)
+	}
+
+	r.db.markWrite(ctx)
+	r.db.usersChanged(ctx, id)
+	return nil
+}
@@ -0,0 +1,44 @@
+package features
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// Flags holds the enabled feature flags. The set can be replaced while it is read, so flags
+// change with configuration reloads.
+type Flags struct {
+	enabled atomic.Pointer[map[string]bool]
+}
+
+// NewFlags creates a flag set with the named features enabled
+func NewFlags(enabled []string) *Flags {
+	f := &Flags{}
+	f.Set(enabled)
+	return f
+}
+
+// Set replaces the enabled features
+func (f *Flags) Set(enabled []string) {
+	set := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		set[name] = true
+	}
+	f.enabled.Store(&set)
+}
+
+// Enabled reports whether the named feature is enabled
+func (f *Flags) Enabled(name string) bool {
+	return (*f.enabled.Load())[name]
+}
+
+// List returns the enabled features in name order
+func (f *Flags) List() []string {
+	set := *f.enabled.Load()
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
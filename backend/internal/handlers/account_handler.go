@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"goapi/internal/auth"
+	"goapi/internal/models"
+	"goapi/internal/services"
+)
+
+// AccountHandler handles HTTP requests for email verification and password resets
+type AccountHandler struct {
+	accountService *services.AccountService
+	orgService     *services.OrganizationService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *services.AccountService, orgService *services.OrganizationService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		orgService:     orgService,
+	}
+}
+
+// VerifyEmail handles POST /api/auth/verify-email
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Token == "" {
+		models.WriteValidationError(w, "Token is required")
+		return
+	}
+
+	if err := h.accountService.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidAccountToken) {
+			models.WriteValidationError(w, "Invalid or expired verification token")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to verify email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification handles POST /api/auth/verify-email/resend
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	if err := h.accountService.SendVerificationEmail(r.Context(), principal.OrgID, principal.UserID); err != nil {
+		models.WriteInternalServerError(w, "Failed to send verification email")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPassword handles POST /api/auth/password/forgot
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Email == "" {
+		models.WriteValidationError(w, "Email is required")
+		return
+	}
+
+	orgID, err := resolveOrgID(r, h.orgService, req.Organization)
+	if err != nil {
+		if errors.Is(err, services.ErrTenantRequired) {
+			models.WriteValidationError(w, "Organization is required")
+			return
+		}
+		// Unknown organizations are treated like unknown emails
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(r.Context(), orgID, req.Email); err != nil {
+		models.WriteInternalServerError(w, "Failed to send password reset email")
+		return
+	}
+
+	// Always accepted so callers cannot tell whether the email is registered
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /api/auth/password/reset
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		models.WriteValidationError(w, "Token and password are required")
+		return
+	}
+
+	if len(req.Password) < 8 {
+		models.WriteValidationError(w, "Password must be at least 8 characters")
+		return
+	}
+
+	if err := h.accountService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidAccountToken) {
+			models.WriteValidationError(w, "Invalid or expired reset token")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to reset password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
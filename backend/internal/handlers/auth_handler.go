@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"goapi/internal/auth"
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// AuthHandler handles HTTP requests for login and MFA operations
+type AuthHandler struct {
+	authService *services.AuthService
+	orgService  *services.OrganizationService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(authService *services.AuthService, orgService *services.OrganizationService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		orgService:  orgService,
+	}
+}
+
+// Login handles POST /api/auth/login
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		models.WriteValidationError(w, "Email and password are required")
+		return
+	}
+
+	orgID, err := resolveOrgID(r, h.orgService, req.Organization)
+	if err != nil {
+		if errors.Is(err, services.ErrTenantRequired) {
+			models.WriteValidationError(w, "Organization is required")
+			return
+		}
+		models.WriteUnauthorizedError(w, "Invalid email or password")
+		return
+	}
+
+	result, err := h.authService.Login(r.Context(), orgID, req, utils.ClientIP(r))
+	if err != nil {
+		if writeThrottledError(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			models.WriteUnauthorizedError(w, "Invalid email or password")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to log in")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// VerifyMFA handles POST /api/auth/mfa/verify
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.MFAToken == "" || req.Code == "" {
+		models.WriteValidationError(w, "MFA token and code are required")
+		return
+	}
+
+	result, err := h.authService.VerifyMFA(r.Context(), req, utils.ClientIP(r))
+	if err != nil {
+		if writeThrottledError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrExpiredToken):
+			models.WriteUnauthorizedError(w, "Invalid or expired MFA token")
+		case errors.Is(err, services.ErrInvalidMFACode), errors.Is(err, services.ErrMFANotEnrolled):
+			models.WriteUnauthorizedError(w, "Invalid MFA code")
+		default:
+			models.WriteInternalServerError(w, "Failed to verify MFA code")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// EnrollMFA handles POST /api/auth/mfa/enroll
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	result, err := h.authService.EnrollMFA(r.Context(), principal)
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			models.WriteConflictError(w, "MFA is already enabled")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to start MFA enrollment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// MFAQRCode handles GET /api/auth/mfa/qr
+func (h *AuthHandler) MFAQRCode(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	png, err := h.authService.MFAQRCode(r.Context(), principal)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMFANotEnrolled):
+			models.WriteNotFoundError(w, "MFA enrollment")
+		case errors.Is(err, services.ErrMFAAlreadyEnabled):
+			models.WriteConflictError(w, "MFA is already enabled")
+		default:
+			models.WriteInternalServerError(w, "Failed to render QR code")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+// ConfirmMFA handles POST /api/auth/mfa/confirm
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Code == "" {
+		models.WriteValidationError(w, "Code is required")
+		return
+	}
+
+	result, err := h.authService.ConfirmMFA(r.Context(), principal, req.Code)
+	if err != nil {
+		writeMFAError(w, err, "Failed to confirm MFA enrollment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// RegenerateRecoveryCodes handles POST /api/auth/mfa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Code == "" {
+		models.WriteValidationError(w, "Code is required")
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), principal.UserID, req.Code)
+	if err != nil {
+		writeMFAError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    map[string]interface{}{"recovery_codes": codes},
+	})
+}
+
+// UnlockUser handles POST /api/users/{id}/unlock
+func (h *AuthHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		models.WriteValidationError(w, "Invalid user ID")
+		return
+	}
+
+	if err := h.authService.UnlockUser(r.Context(), principal.OrgID, id, principal.UserID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			models.WriteNotFoundError(w, "User")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to unlock user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeThrottledError writes a 429 response with Retry-After if err is a ThrottledError
+func writeThrottledError(w http.ResponseWriter, err error) bool {
+	var throttled *services.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	message := "Too many failed attempts, please wait before retrying"
+	if throttled.Locked {
+		message = "Too many failed attempts, login is temporarily locked"
+	}
+	models.WriteTooManyRequestsError(w, message)
+	return true
+}
+
+// writeMFAError maps MFA service errors to HTTP error responses
+func writeMFAError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		models.WriteValidationError(w, "Invalid MFA code")
+	case errors.Is(err, services.ErrMFANotEnrolled):
+		models.WriteNotFoundError(w, "MFA enrollment")
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		models.WriteConflictError(w, "MFA is already enabled")
+	default:
+		models.WriteInternalServerError(w, fallback)
+	}
+}
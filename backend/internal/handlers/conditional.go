@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/internal/models"
+)
+
+// notModified sets the validators of a GET response and reports whether the request's
+// conditions show that the client's copy is current, in which case it has written 304. Empty
+// validators are not sent. As RFC 9110 requires, If-Modified-Since is ignored when the request
+// also has If-None-Match, and Last-Modified is compared to the second as HTTP dates are.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	header := w.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := strings.Join(r.Header.Values("If-None-Match"), ","); ifNoneMatch != "" {
+		if etag == "" || !etagMatches(ifNoneMatch, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match list holds etag, using the weak comparison
+// If-None-Match calls for
+func etagMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// userETag identifies a version of a user; updated_at changes with every update, at a finer
+// resolution than Last-Modified
+func userETag(user *models.UserResponse) string {
+	return `W/"` + strconv.Itoa(user.ID) + "-" + strconv.FormatInt(user.UpdatedAt.UnixNano(), 36) + `"`
+}
+
+// usersETag identifies a version of an organization's user list
+func usersETag(orgID int, version models.UserListVersion) string {
+	etag := `W/"` + strconv.Itoa(orgID) + "-" + strconv.Itoa(version.Count)
+	if version.LastModified != nil {
+		etag += "-" + strconv.FormatInt(version.LastModified.UnixNano(), 36)
+	}
+	return etag + `"`
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"goapi/internal/models"
+)
+
+// errTrailingData is returned when a request body holds more than one JSON value
+var errTrailingData = errors.New("request body must contain a single JSON object")
+
+// decodeJSONStrict decodes a request body holding exactly one JSON value into dst,
+// rejecting fields dst does not declare
+func decodeJSONStrict(r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return errTrailingData
+	}
+	return nil
+}
+
+// writeDecodeError reports a body decodeJSONStrict rejected: 413 when it exceeds the size
+// limit and 400 with the reason otherwise
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var (
+		maxBytesErr *http.MaxBytesError
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		models.WritePayloadTooLargeError(w, fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, io.EOF):
+		models.WriteValidationError(w, "Request body must not be empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		models.WriteValidationError(w, "Invalid JSON payload")
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			models.WriteValidationError(w, fmt.Sprintf("Invalid value for field %q", typeErr.Field))
+			return
+		}
+		models.WriteValidationError(w, "Request body must be a JSON object")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		models.WriteValidationError(w, "Unknown field "+field)
+	case errors.Is(err, errTrailingData):
+		models.WriteValidationError(w, "Request body must contain a single JSON object")
+	default:
+		models.WriteValidationError(w, "Invalid JSON payload")
+	}
+}
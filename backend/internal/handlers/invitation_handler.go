@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"goapi/internal/auth"
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/internal/tenant"
+	"goapi/pkg/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// InvitationHandler handles HTTP requests for user invitations
+type InvitationHandler struct {
+	invitationService *services.InvitationService
+}
+
+// NewInvitationHandler creates a new invitation handler
+func NewInvitationHandler(invitationService *services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+	}
+}
+
+// CreateInvitation handles POST /api/invitations
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	var req models.CreateInvitationRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.Email == "" || !utils.IsValidEmail(req.Email) {
+		models.WriteValidationError(w, "A valid email is required")
+		return
+	}
+
+	if req.Role != "" && !models.IsValidRole(req.Role) {
+		models.WriteValidationError(w, "Invalid role")
+		return
+	}
+
+	if !canAssignRole(r, req.Role) {
+		models.WriteForbiddenError(w, "Insufficient permissions to assign role")
+		return
+	}
+
+	invitation, err := h.invitationService.CreateInvitation(r.Context(), tenant.OrgID(r.Context()), principal.UserID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailExists) {
+			models.WriteConflictError(w, "Email already exists")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to create invitation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    invitation,
+	})
+}
+
+// GetInvitations handles GET /api/invitations
+func (h *InvitationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.invitationService.GetPendingInvitations(r.Context(), tenant.OrgID(r.Context()))
+	if err != nil {
+		models.WriteInternalServerError(w, "Failed to retrieve invitations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    invitations,
+	})
+}
+
+// RevokeInvitation handles DELETE /api/invitations/{id}
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		models.WriteValidationError(w, "Invalid invitation ID")
+		return
+	}
+
+	if err := h.invitationService.RevokeInvitation(r.Context(), tenant.OrgID(r.Context()), id); err != nil {
+		if errors.Is(err, services.ErrInvitationNotFound) {
+			models.WriteNotFoundError(w, "Invitation")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to revoke invitation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation handles POST /api/invitations/{token}/accept
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.AcceptInvitationRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.Name == "" || req.Password == "" {
+		models.WriteValidationError(w, "Name and password are required")
+		return
+	}
+
+	if len(req.Password) < 8 {
+		models.WriteValidationError(w, "Password must be at least 8 characters")
+		return
+	}
+
+	user, err := h.invitationService.AcceptInvitation(r.Context(), vars["token"], req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInvitation):
+			models.WriteNotFoundError(w, "Invitation")
+		case errors.Is(err, services.ErrEmailExists):
+			models.WriteConflictError(w, "Email already exists")
+		default:
+			models.WriteInternalServerError(w, "Failed to accept invitation")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    user,
+	})
+}
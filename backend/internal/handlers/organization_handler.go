@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// OrganizationHandler handles HTTP requests for organization operations
+type OrganizationHandler struct {
+	orgService *services.OrganizationService
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+	}
+}
+
+// GetOrganizations handles GET /api/organizations
+func (h *OrganizationHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.orgService.GetAllOrganizations(r.Context())
+	if err != nil {
+		models.WriteInternalServerError(w, "Failed to retrieve organizations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    orgs,
+	})
+}
+
+// GetOrganization handles GET /api/organizations/{id}
+func (h *OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		models.WriteValidationError(w, "Invalid organization ID")
+		return
+	}
+
+	org, err := h.orgService.GetOrganizationByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrOrganizationNotFound) {
+			models.WriteNotFoundError(w, "Organization")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to retrieve organization")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    org,
+	})
+}
+
+// CreateOrganization handles POST /api/organizations
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Name == "" || !utils.IsValidSlug(req.Slug) {
+		models.WriteValidationError(w, "Name and a valid slug are required")
+		return
+	}
+
+	org, err := h.orgService.CreateOrganization(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrSlugExists) {
+			models.WriteConflictError(w, "Slug already exists")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to create organization")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    org,
+	})
+}
+
+// UpdateOrganization handles PUT /api/organizations/{id}
+func (h *OrganizationHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		models.WriteValidationError(w, "Invalid organization ID")
+		return
+	}
+
+	var req models.UpdateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		models.WriteValidationError(w, "Invalid JSON payload")
+		return
+	}
+
+	if req.Name == "" || !utils.IsValidSlug(req.Slug) {
+		models.WriteValidationError(w, "Name and a valid slug are required")
+		return
+	}
+
+	org, err := h.orgService.UpdateOrganization(r.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrganizationNotFound):
+			models.WriteNotFoundError(w, "Organization")
+		case errors.Is(err, services.ErrSlugExists):
+			models.WriteConflictError(w, "Slug already exists")
+		default:
+			models.WriteInternalServerError(w, "Failed to update organization")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    org,
+	})
+}
+
+// DeleteOrganization handles DELETE /api/organizations/{id}
+func (h *OrganizationHandler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		models.WriteValidationError(w, "Invalid organization ID")
+		return
+	}
+
+	if err := h.orgService.DeleteOrganization(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrOrganizationNotFound) {
+			models.WriteNotFoundError(w, "Organization")
+			return
+		}
+		models.WriteInternalServerError(w, "Failed to delete organization")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/internal/auth"
+	"goapi/internal/models"
+)
+
+// canAssignRole reports whether the caller may grant role to another user.
+// Elevated roles can only be granted by a caller holding at least that role.
+func canAssignRole(r *http.Request, role string) bool {
+	if role != models.RoleAdmin && role != models.RoleSuperAdmin {
+		return true
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok || principal.TokenType != auth.TokenTypeAccess {
+		return false
+	}
+
+	return principal.Role == models.RoleSuperAdmin ||
+		(principal.Role == models.RoleAdmin && role == models.RoleAdmin)
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ndjsonContentType is newline-delimited JSON: one value per line, no envelope
+	ndjsonContentType = "application/x-ndjson"
+
+	// streamFlushInterval and streamFlushItems bound how long items wait in the buffers of the
+	// response writers before being sent, whichever is reached first
+	streamFlushInterval = 250 * time.Millisecond
+	streamFlushItems    = 500
+)
+
+// Envelope framing around streamed items, matching what encoding a
+// {"success": true, "data": [...]} map produces
+const (
+	listPrefix = `{"data":[`
+	listSuffix = "],\"success\":true}\n"
+)
+
+// listStream writes a list response item by item as the items are produced, so its size does
+// not depend on how many there are. Items are sent in the usual JSON envelope or, when the
+// client prefers application/x-ndjson, one per line.
+//
+// Nothing is written until the first item, so a failure before it can still be answered with
+// an error status. Writes block while the client is slow to read, which holds back the producer;
+// once the client has gone away Write fails with the request's error and the producer stops.
+// The server's write timeout is renewed on every flush, so it limits how long the client may
+// stall rather than how long the whole list takes.
+type listStream struct {
+	w            http.ResponseWriter
+	r            *http.Request
+	rc           *http.ResponseController
+	ndjson       bool
+	started      bool
+	writeTimeout time.Duration
+	err          error
+
+	pending   int
+	lastFlush time.Time
+}
+
+// newListStream prepares a list response to r, negotiating its format from the Accept header
+func newListStream(w http.ResponseWriter, r *http.Request) *listStream {
+	s := &listStream{
+		w:      w,
+		r:      r,
+		rc:     http.NewResponseController(w),
+		ndjson: prefersNDJSON(r.Header.Get("Accept")),
+	}
+	if server, ok := r.Context().Value(http.ServerContextKey).(*http.Server); ok {
+		s.writeTimeout = server.WriteTimeout
+	}
+
+	// Set now so that responses ending before the list starts, such as 304s, carry it too
+	w.Header().Add("Vary", "Accept")
+	return s
+}
+
+// Write sends one item
+func (s *listStream) Write(item interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.r.Context().Err(); err != nil {
+		s.err = err
+		return err
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case s.ndjson:
+		data = append(data, '\n')
+	case s.started:
+		data = append([]byte{','}, data...)
+	}
+
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.write(data); err != nil {
+		return err
+	}
+
+	s.pending++
+	if s.pending >= streamFlushItems || time.Since(s.lastFlush) >= streamFlushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+// Close ends the list and flushes what is left
+func (s *listStream) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.start(); err != nil {
+		return err
+	}
+	if !s.ndjson {
+		if err := s.write([]byte(listSuffix)); err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+// Err returns the error that ended the stream on the client's side: the request was
+// cancelled or the connection failed. Nothing more can be sent after it.
+func (s *listStream) Err() error {
+	return s.err
+}
+
+// Started reports whether the response has been sent, after which its status cannot change
+func (s *listStream) Started() bool {
+	return s.started
+}
+
+// start writes the headers and the opening of the envelope
+func (s *listStream) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	s.lastFlush = time.Now()
+
+	if s.ndjson {
+		s.w.Header().Set("Content-Type", ndjsonContentType)
+		return nil
+	}
+
+	s.w.Header().Set("Content-Type", "application/json")
+	return s.write([]byte(listPrefix))
+}
+
+// write sends data, remembering a failure as the stream's error
+func (s *listStream) write(data []byte) error {
+	if _, err := s.w.Write(data); err != nil {
+		s.err = err
+		return err
+	}
+	return nil
+}
+
+// flush sends the buffered items to the client. Writers that cannot flush leave them to be
+// sent when their buffers fill or the response ends.
+func (s *listStream) flush() error {
+	s.pending = 0
+	s.lastFlush = time.Now()
+
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.err = err
+		return err
+	}
+	if s.writeTimeout > 0 {
+		if err := s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			s.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// prefersNDJSON reports whether the Accept header weights application/x-ndjson at least as
+// high as application/json. Without an explicit NDJSON entry the answer is always no, so
+// wildcards keep getting the JSON envelope.
+func prefersNDJSON(accept string) bool {
+	ndjsonQ, jsonQ, wildcardQ := -1.0, -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch name {
+		case ndjsonContentType:
+			ndjsonQ = q
+		case "application/json":
+			jsonQ = q
+		case "application/*", "*/*":
+			wildcardQ = max(wildcardQ, q)
+		}
+	}
+
+	if jsonQ < 0 {
+		jsonQ = wildcardQ
+	}
+	return ndjsonQ > 0 && ndjsonQ >= jsonQ
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/logger"
+)
+
+// streamStore is a user store whose Stream produces users and then fails with err, if set
+type streamStore struct {
+	services.UserStore
+	users []models.User
+	err   error
+}
+
+func (s *streamStore) Version(ctx context.Context, orgID int) (models.UserListVersion, error) {
+	return models.UserListVersion{Count: len(s.users)}, nil
+}
+
+func (s *streamStore) Stream(ctx context.Context, orgID int, fn func(*models.User) error) error {
+	for i := range s.users {
+		if err := fn(&s.users[i]); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func newStreamHandler(t *testing.T, store *streamStore) http.Handler {
+	t.Helper()
+	log, err := logger.New(logger.Options{Level: "error", Output: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	return http.HandlerFunc(NewUserHandler(services.NewUserService(store, nil, log)).GetUsers)
+}
+
+func TestGetUsersStream(t *testing.T) {
+	users := []models.User{{ID: 1, Name: "Ada"}, {ID: 2, Name: "Grace"}}
+	failure := errors.New("connection reset by database")
+
+	tests := []struct {
+		name   string
+		accept string
+		users  []models.User
+		err    error
+		// wantAbort is whether the response is aborted rather than ended normally
+		wantAbort       bool
+		wantStatus      int
+		wantContentType string
+		wantBody        []string
+	}{
+		{
+			name:            "json envelope",
+			users:           users,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        []string{`{"data":[{"id":1,`, `},{"id":2,`, "}],\"success\":true}\n"},
+		},
+		{
+			name:            "ndjson",
+			accept:          "application/x-ndjson",
+			users:           users,
+			wantStatus:      http.StatusOK,
+			wantContentType: ndjsonContentType,
+			wantBody:        []string{`{"id":1,`, "}\n{\"id\":2,"},
+		},
+		{
+			name:            "empty list",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        []string{"{\"data\":[],\"success\":true}\n"},
+		},
+		{
+			name:       "failure before the first user",
+			err:        failure,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   []string{"Failed to retrieve users"},
+		},
+		{
+			name:      "json failure after the first user",
+			users:     users,
+			err:       failure,
+			wantAbort: true,
+		},
+		{
+			name:      "ndjson failure after the first user",
+			accept:    "application/x-ndjson",
+			users:     users,
+			err:       failure,
+			wantAbort: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newStreamHandler(t, &streamStore{users: tt.users, err: tt.err})
+			req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			aborted := func() (aborted bool) {
+				defer func() {
+					if v := recover(); v != nil {
+						if v != http.ErrAbortHandler {
+							panic(v)
+						}
+						aborted = true
+					}
+				}()
+				handler.ServeHTTP(rec, req)
+				return false
+			}()
+
+			if aborted != tt.wantAbort {
+				t.Fatalf("aborted = %v, want %v", aborted, tt.wantAbort)
+			}
+			if tt.wantAbort {
+				return
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantContentType != "" && rec.Header().Get("Content-Type") != tt.wantContentType {
+				t.Fatalf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.wantContentType)
+			}
+			if tt.wantStatus != http.StatusOK && rec.Header().Get("ETag") != "" {
+				t.Fatalf("error response carries ETag %q", rec.Header().Get("ETag"))
+			}
+			for _, part := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), part) {
+					t.Fatalf("body %q does not contain %q", rec.Body.String(), part)
+				}
+			}
+		})
+	}
+}
+
+// TestGetUsersStreamAbortReachesClient checks that a list that fails part way is seen by the
+// client as a broken response rather than a complete, shorter list
+func TestGetUsersStreamAbortReachesClient(t *testing.T) {
+	for _, accept := range []string{"application/json", "application/x-ndjson"} {
+		t.Run(accept, func(t *testing.T) {
+			// Enough users to be flushed, so the client has the status and part of the list
+			users := make([]models.User, streamFlushItems)
+			for i := range users {
+				users[i] = models.User{ID: i + 1, Name: "User"}
+			}
+			server := httptest.NewServer(newStreamHandler(t, &streamStore{
+				users: users,
+				err:   errors.New("connection reset by database"),
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("Accept", accept)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			if body, err := io.ReadAll(resp.Body); err == nil {
+				t.Fatalf("truncated body of %d bytes was read without an error", len(body))
+			}
+		})
+	}
+}
+
+func TestPrefersNDJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/x-ndjson", true},
+		{"application/json, application/x-ndjson", true},
+		{"application/json, application/x-ndjson;q=0.5", false},
+		{"application/json;q=0.5, application/x-ndjson", true},
+		{"application/x-ndjson;q=0.5, */*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := prefersNDJSON(tt.accept); got != tt.want {
+				t.Fatalf("prefersNDJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/internal/services"
+	"goapi/internal/tenant"
+)
+
+// resolveOrgID returns the organization named by ref, falling back to the request's tenant scope
+func resolveOrgID(r *http.Request, orgs *services.OrganizationService, ref string) (int, error) {
+	if ref != "" {
+		return orgs.ResolveTenant(r.Context(), ref)
+	}
+
+	orgID, ok := tenant.OrgIDFromContext(r.Context())
+	if !ok {
+		return 0, services.ErrTenantRequired
+	}
+	return orgID, nil
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
+	"goapi/internal/consistency"
 	"goapi/internal/models"
 	"goapi/internal/services"
+	"goapi/internal/tenant"
 
 	"github.com/gorilla/mux"
 )
@@ -23,19 +26,46 @@ func NewUserHandler(userService *services.UserService) *UserHandler {
 	}
 }
 
-// GetUsers handles GET /api/users
+// GetUsers handles GET /api/users. Users are streamed as they are read from the database, in
+// the usual JSON envelope or, for Accept: application/x-ndjson, one per line. The list's ETag
+// comes from a cheap summary query, so unchanged lists are answered with 304 without reading them.
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.userService.GetAllUsers()
+	// Read the summary and the list from the same database so the ETag describes the list sent
+	ctx := consistency.WithPinnedReads(r.Context())
+	orgID := tenant.OrgID(ctx)
+
+	version, err := h.userService.GetUsersVersion(ctx, orgID)
 	if err != nil {
 		models.WriteInternalServerError(w, "Failed to retrieve users")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"data":    users,
+	stream := newListStream(w, r)
+	if notModified(w, r, usersETag(orgID, version), time.Time{}) {
+		return
+	}
+
+	err = h.userService.StreamUsers(ctx, orgID, func(user models.UserResponse) error {
+		return stream.Write(user)
 	})
+	if err == nil {
+		err = stream.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	switch {
+	case stream.Err() != nil:
+		// The client went away; there is no one left to tell
+	case !stream.Started():
+		w.Header().Del("ETag")
+		models.WriteInternalServerError(w, "Failed to retrieve users")
+	default:
+		// The status has been sent, so abort the connection rather than end the body normally:
+		// a truncated list must not look complete, NDJSON especially
+		panic(http.ErrAbortHandler)
+	}
 }
 
 // GetUser handles GET /api/users/{id}
@@ -47,12 +77,16 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(id)
+	user, err := h.userService.GetUserByID(r.Context(), tenant.OrgID(r.Context()), id)
 	if err != nil {
 		models.WriteNotFoundError(w, "User")
 		return
 	}
 
+	if notModified(w, r, userETag(user), user.UpdatedAt) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -63,8 +97,8 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 // CreateUser handles POST /api/users
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		models.WriteValidationError(w, "Invalid JSON payload")
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -74,7 +108,22 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(req)
+	if req.Role != "" && !models.IsValidRole(req.Role) {
+		models.WriteValidationError(w, "Invalid role")
+		return
+	}
+
+	if !canAssignRole(r, req.Role) {
+		models.WriteForbiddenError(w, "Insufficient permissions to assign role")
+		return
+	}
+
+	if req.Password != "" && len(req.Password) < 8 {
+		models.WriteValidationError(w, "Password must be at least 8 characters")
+		return
+	}
+
+	user, err := h.userService.CreateUser(r.Context(), tenant.OrgID(r.Context()), req)
 	if err != nil {
 		if err.Error() == "email already exists" {
 			models.WriteConflictError(w, "Email already exists")
@@ -102,8 +151,8 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		models.WriteValidationError(w, "Invalid JSON payload")
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -113,7 +162,7 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(id, req)
+	user, err := h.userService.UpdateUser(r.Context(), tenant.OrgID(r.Context()), id, req)
 	if err != nil {
 		if err.Error() == "user not found" {
 			models.WriteNotFoundError(w, "User")
@@ -143,7 +192,7 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userService.DeleteUser(id)
+	err = h.userService.DeleteUser(r.Context(), tenant.OrgID(r.Context()), id)
 	if err != nil {
 		if err.Error() == "user not found" {
 			models.WriteNotFoundError(w, "User")
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"goapi/pkg/logger"
+)
+
+// FileMailer writes each message as an .eml file, for local development and tests
+type FileMailer struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// NewFileMailer creates a mailer that writes messages into dir
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+// Send writes the message to a new file in the mailer's directory
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	body, err := buildMIME(msg)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%04d-%s.eml", time.Now().UTC().Format("20060102T150405"), m.seq.Add(1), sanitizeFilename(msg.To))
+	if err := os.WriteFile(filepath.Join(m.dir, name), body, 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return nil
+}
+
+// LogMailer logs messages instead of sending them
+type LogMailer struct {
+	log logger.Logger
+}
+
+// NewLogMailer creates a mailer that logs messages
+func NewLogMailer(log logger.Logger) *LogMailer {
+	return &LogMailer{log: log}
+}
+
+// Send logs the message recipient, subject and text body
+func (m *LogMailer) Send(msg Message) error {
+	m.log.Info("Email sent", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+// sanitizeFilename replaces characters that are unsafe in file names
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
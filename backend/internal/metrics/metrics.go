@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the application's Prometheus collectors
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
+	dbDuration   *prometheus.HistogramVec
+	dbReplicaUp  *prometheus.GaugeVec
+
+	cacheLookups   *prometheus.CounterVec
+	cacheEvictions *prometheus.CounterVec
+}
+
+// NewMetrics creates the application metrics on a dedicated registry,
+// together with the standard Go runtime and process collectors
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route template, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		httpInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served by route template and method.",
+		}, []string{"route", "method"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_operation_duration_seconds",
+			Help:    "Repository method latency by operation and outcome.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "outcome"}),
+		dbReplicaUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "db_replica_healthy",
+			Help: "Whether a read replica passed its last health check (1) or not (0).",
+		}, []string{"replica"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_lookups_total",
+			Help: "Cache lookups by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		cacheEvictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_evictions_total",
+			Help: "Entries evicted to make room, by cache.",
+		}, []string{"cache"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.dbDuration,
+		m.dbReplicaUp,
+		m.cacheLookups,
+		m.cacheEvictions,
+	)
+
+	return m
+}
+
+// Handler serves the registered metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports connection pool statistics (open, in use, idle, wait count and duration) for db
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// SetDBReplicaHealthy records the health of a read replica
+func (m *Metrics) SetDBReplicaHealthy(replica string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	m.dbReplicaUp.WithLabelValues(replica).Set(value)
+}
+
+// ObserveCacheLookup counts a cache hit or miss
+func (m *Metrics) ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveCacheEviction counts an entry evicted from a full cache
+func (m *Metrics) ObserveCacheEviction(cache string) {
+	m.cacheEvictions.WithLabelValues(cache).Inc()
+}
+
+// RequestStarted marks a request as in flight and returns a function that records its outcome
+func (m *Metrics) RequestStarted(route, method string) func(status int) {
+	start := time.Now()
+	inFlight := m.httpInFlight.WithLabelValues(route, method)
+	inFlight.Inc()
+
+	return func(status int) {
+		inFlight.Dec()
+		code := strconv.Itoa(status)
+		m.httpRequests.WithLabelValues(route, method, code).Inc()
+		m.httpDuration.WithLabelValues(route, method, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveDBOperation records the latency of a repository method
+func (m *Metrics) ObserveDBOperation(operation string, duration time.Duration, failed bool) {
+	outcome := "success"
+	if failed {
+		outcome = "error"
+	}
+	m.dbDuration.WithLabelValues(operation, outcome).Observe(duration.Seconds())
+}
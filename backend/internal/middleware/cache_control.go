@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+
+	"goapi/internal/config"
+
+	"github.com/gorilla/mux"
+)
+
+// NewCacheControl creates a middleware setting Cache-Control from the policy of the request's
+// route, or the default policy for GET routes without one. Only successful and 304 responses
+// get the policy, so errors are never cached, and handlers that set the header themselves,
+// such as for tokens, keep theirs.
+func NewCacheControl(cfg config.CacheControlConfig, router *mux.Router) (func(http.Handler) http.Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	defaultPolicy, err := cfg.DefaultPolicy()
+	if err != nil {
+		return nil, err
+	}
+	routes, err := cfg.RoutePolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy, ok := routes[r.Method+" "+routeTemplate(router, r)]
+			if !ok && r.Method == http.MethodGet {
+				policy = defaultPolicy
+			}
+			if policy == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, policy: policy}, r)
+		})
+	}, nil
+}
+
+// cacheControlWriter adds the policy when the response status is written
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (cw *cacheControlWriter) WriteHeader(code int) {
+	// Informational responses precede the final one
+	if !cw.wroteHeader && code >= 200 {
+		cw.wroteHeader = true
+
+		header := cw.Header()
+		cacheable := (code >= 200 && code < 300) || code == http.StatusNotModified
+		if cacheable && header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", cw.policy)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheControlWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer so streaming handlers keep working
+func (cw *cacheControlWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"goapi/internal/config"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported content codings, in order of preference when the client weights them equally
+var encodingPreference = []string{"br", "gzip", "deflate"}
+
+// Compression compresses responses with brotli, gzip or deflate as negotiated from Accept-Encoding.
+// Bodies are buffered until MinSize bytes are written, so small responses are sent as is, and only
+// allowlisted content types are compressed. Responses that might be compressed carry
+// "Vary: Accept-Encoding" so caches keep the variants apart.
+func Compression(cfg config.CompressionConfig) func(http.Handler) http.Handler {
+	c := &compressor{
+		minSize: cfg.MinSize,
+		types:   make(map[string]bool, len(cfg.ContentTypes)),
+		level:   cfg.Level,
+	}
+	for _, t := range cfg.ContentTypes {
+		c.types[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				compressor:     c,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+			}
+			defer cw.finish()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressor holds the compression settings and encoder pools
+type compressor struct {
+	minSize int
+	types   map[string]bool
+	level   int
+
+	gzipPool   sync.Pool
+	zlibPool   sync.Pool
+	brotliPool sync.Pool
+}
+
+// compressible reports whether the media type of contentType is allowlisted
+func (c *compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if c.types[mediaType] {
+		return true
+	}
+
+	// Entries such as "text/*" allow every subtype
+	major, _, _ := strings.Cut(mediaType, "/")
+	return c.types[major+"/*"]
+}
+
+// encoder returns a pooled encoder for the coding writing to w
+func (c *compressor) encoder(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "br":
+		if enc, ok := c.brotliPool.Get().(*brotli.Writer); ok {
+			enc.Reset(w)
+			return &pooledEncoder{WriteCloser: enc, pool: &c.brotliPool}
+		}
+		return &pooledEncoder{WriteCloser: brotli.NewWriterLevel(w, brotliLevel(c.level)), pool: &c.brotliPool}
+	case "gzip":
+		if enc, ok := c.gzipPool.Get().(*gzip.Writer); ok {
+			enc.Reset(w)
+			return &pooledEncoder{WriteCloser: enc, pool: &c.gzipPool}
+		}
+		enc, err := gzip.NewWriterLevel(w, c.level)
+		if err != nil {
+			enc = gzip.NewWriter(w)
+		}
+		return &pooledEncoder{WriteCloser: enc, pool: &c.gzipPool}
+	default:
+		if enc, ok := c.zlibPool.Get().(*zlib.Writer); ok {
+			enc.Reset(w)
+			return &pooledEncoder{WriteCloser: enc, pool: &c.zlibPool}
+		}
+		enc, err := zlib.NewWriterLevel(w, c.level)
+		if err != nil {
+			enc = zlib.NewWriter(w)
+		}
+		return &pooledEncoder{WriteCloser: enc, pool: &c.zlibPool}
+	}
+}
+
+// brotliLevel maps a gzip-style level (-1 to 9) onto brotli's 0 to 11 scale
+func brotliLevel(level int) int {
+	if level < 0 {
+		return brotli.DefaultCompression
+	}
+	return level * brotli.BestCompression / gzip.BestCompression
+}
+
+// flusher is implemented by every supported encoder
+type flusher interface {
+	Flush() error
+}
+
+// pooledEncoder returns its encoder to the pool when closed
+type pooledEncoder struct {
+	io.WriteCloser
+	pool *sync.Pool
+}
+
+func (e *pooledEncoder) Flush() error {
+	return e.WriteCloser.(flusher).Flush()
+}
+
+func (e *pooledEncoder) Close() error {
+	err := e.WriteCloser.Close()
+	e.pool.Put(e.WriteCloser)
+	return err
+}
+
+// compressWriter buffers the start of the body to decide whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *compressor
+	encoding   string
+
+	status  int
+	decided bool
+	buf     []byte
+	encoder io.WriteCloser
+}
+
+// WriteHeader records the status; headers are sent once the body is large enough to decide
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	cw.status = code
+
+	// Informational responses are sent immediately and do not carry the body
+	if code >= 100 && code < 200 {
+		cw.status = 0
+		cw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.compressor.minSize {
+			return len(p), nil
+		}
+
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data, compressing it when eligible regardless of size since the
+// handler is streaming
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+
+	if f, ok := cw.encoder.(flusher); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Hijack passes through to the underlying writer; hijacked connections are never compressed
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+// decide sends the headers, switching to a compressed body when the response is eligible,
+// and writes out the buffered data
+func (cw *compressWriter) decide(largeEnough bool) error {
+	cw.decided = true
+	header := cw.Header()
+
+	// Mirror net/http, which would otherwise sniff the type from the first write
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if bodyAllowed(cw.status) && header.Get("Content-Encoding") == "" &&
+		cw.compressor.compressible(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+
+		if cw.encoding != "" && largeEnough {
+			header.Set("Content-Encoding", cw.encoding)
+			header.Del("Content-Length")
+			header.Del("Accept-Ranges")
+			// A compressed body is a different representation, so strong validators become weak
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+			cw.encoder = cw.compressor.encoder(cw.encoding, cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// finish sends any buffered response and closes the encoder
+func (cw *compressWriter) finish() {
+	if !cw.decided && cw.status != 0 {
+		cw.decide(false)
+	}
+
+	if cw.encoder != nil {
+		cw.encoder.Close()
+	}
+}
+
+// bodyAllowed reports whether a response with the status carries a body
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= 200
+}
+
+// negotiateEncoding picks the supported coding with the highest weight in Accept-Encoding,
+// or "" when the client accepts none of them
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodingPreference {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
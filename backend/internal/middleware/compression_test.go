@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goapi/internal/config"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"GZIP", "gzip"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.1, deflate", "deflate"},
+		{"br;q=0, *", "gzip"},
+		{"gzip;q=abc, deflate", "deflate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+				t.Fatalf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat(`{"name":"John Doe","email":"john@example.com"}`, 100)
+
+	compress := Compression(config.CompressionConfig{
+		MinSize:      1024,
+		Level:        -1,
+		ContentTypes: []string{"application/json", "text/*"},
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		status         int
+		header         map[string]string
+		body           string
+		wantEncoding   string
+		wantVary       bool
+		wantETag       string
+	}{
+		{name: "negotiated coding", acceptEncoding: "gzip", body: large, wantEncoding: "gzip", wantVary: true},
+		{name: "preferred coding", acceptEncoding: "gzip, deflate, br", body: large, wantEncoding: "br", wantVary: true},
+		{name: "deflate", acceptEncoding: "deflate", body: large, wantEncoding: "deflate", wantVary: true},
+		{name: "no accepted coding", body: large, wantVary: true},
+		{name: "below the minimum size", acceptEncoding: "gzip", body: `{"ok":true}`, wantVary: true},
+		{name: "type wildcard", acceptEncoding: "gzip", header: map[string]string{"Content-Type": "text/csv"}, body: large, wantEncoding: "gzip", wantVary: true},
+		{name: "type not allowed", acceptEncoding: "gzip", header: map[string]string{"Content-Type": "image/png"}, body: large},
+		{name: "already encoded", acceptEncoding: "gzip", header: map[string]string{"Content-Encoding": "br"}, body: large, wantEncoding: "br"},
+		{name: "no content", acceptEncoding: "gzip", status: http.StatusNoContent},
+		{name: "head request", method: http.MethodHead, acceptEncoding: "gzip", body: large},
+		{name: "strong etag weakened", acceptEncoding: "gzip", header: map[string]string{"ETag": `"v1"`}, body: large, wantEncoding: "gzip", wantVary: true, wantETag: `W/"v1"`},
+		{name: "etag kept when not compressed", header: map[string]string{"ETag": `"v1"`}, body: large, wantVary: true, wantETag: `"v1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				for name, value := range tt.header {
+					w.Header().Set(name, value)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				io.WriteString(w, tt.body)
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/api/users", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Fatalf("Vary = %q, want Accept-Encoding %v", rec.Header().Get("Vary"), tt.wantVary)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Fatalf("ETag = %q, want %q", got, tt.wantETag)
+			}
+
+			// Bodies the middleware encoded must decode to what the handler wrote
+			body := rec.Body.Bytes()
+			if tt.header["Content-Encoding"] == "" {
+				body = decode(t, tt.wantEncoding, body)
+			}
+			if string(body) != tt.body {
+				t.Fatalf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+// decode reverses the content coding of a response body
+func decode(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+
+	var r io.Reader = bytes.NewReader(body)
+	var err error
+	switch encoding {
+	case "":
+		return body
+	case "br":
+		r = brotli.NewReader(r)
+	case "gzip":
+		r, err = gzip.NewReader(r)
+	case "deflate":
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		t.Fatalf("failed to read %s body: %v", encoding, err)
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decode %s body: %v", encoding, err)
+	}
+	return decoded
+}
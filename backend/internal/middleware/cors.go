@@ -2,35 +2,74 @@ package middleware
 
 import (
 	"net/http"
+	"sort"
+	"strings"
+
+	"goapi/internal/config"
 
 	"github.com/rs/cors"
 )
 
-// CORSConfig holds CORS configuration
-type CORSConfig struct {
-	AllowedOrigins []string
-	AllowedMethods []string
-	AllowedHeaders []string
+// corsRoute applies its own CORS policy to requests under a path prefix
+type corsRoute struct {
+	prefix string
+	cors   *cors.Cors
 }
 
-// NewCORS creates a new CORS middleware
-func NewCORS(config CORSConfig) func(http.Handler) http.Handler {
-	c := cors.New(cors.Options{
-		AllowedOrigins:   config.AllowedOrigins,
-		AllowedMethods:   config.AllowedMethods,
-		AllowedHeaders:   config.AllowedHeaders,
-		AllowCredentials: false,
-		Debug:            false,
+// NewCORS creates a CORS middleware from configuration. Route overrides replace the allowed
+// origins for their path prefix; the longest matching prefix wins.
+func NewCORS(cfg config.CORSConfig) (func(http.Handler) http.Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := cfg.RouteOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	base := cors.New(corsOptions(cfg, cfg.AllowedOrigins))
+
+	routes := make([]corsRoute, 0, len(overrides))
+	for _, override := range overrides {
+		routes = append(routes, corsRoute{
+			prefix: override.Prefix,
+			cors:   cors.New(corsOptions(cfg, override.AllowedOrigins)),
+		})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
 	})
 
-	return c.Handler
+	return func(next http.Handler) http.Handler {
+		baseHandler := base.Handler(next)
+
+		routeHandlers := make([]http.Handler, len(routes))
+		for i, route := range routes {
+			routeHandlers[i] = route.cors.Handler(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.prefix) {
+					routeHandlers[i].ServeHTTP(w, r)
+					return
+				}
+			}
+			baseHandler.ServeHTTP(w, r)
+		})
+	}, nil
 }
 
-// DefaultCORS returns a default CORS configuration
-func DefaultCORS() func(http.Handler) http.Handler {
-	return NewCORS(CORSConfig{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"*"},
-	})
+// corsOptions builds the options for a set of allowed origins
+func corsOptions(cfg config.CORSConfig, origins []string) cors.Options {
+	return cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+		Debug:            false,
+	}
 }
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goapi/internal/config"
+)
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	cors, err := NewCORS(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods: []string{"GET", "POST"},
+		Routes:         []string{"/api/public=*", "/api/public/private=https://admin.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewCORS: %v", err)
+	}
+	handler := cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		path   string
+		origin string
+		// want is the Access-Control-Allow-Origin header, empty when the origin is refused
+		want string
+	}{
+		{"listed origin", "/api/users", "https://app.example.com", "https://app.example.com"},
+		{"wildcard subdomain", "/api/users", "https://eu.example.org", "https://eu.example.org"},
+		{"unlisted origin", "/api/users", "https://evil.example.com", ""},
+		{"other scheme", "/api/users", "http://app.example.com", ""},
+		{"bare wildcard domain", "/api/users", "https://example.org", ""},
+		{"route override", "/api/public/health", "https://evil.example.com", "*"},
+		{"longest route override wins", "/api/public/private/keys", "https://app.example.com", ""},
+		{"longest route override origin", "/api/public/private/keys", "https://admin.example.com", "https://admin.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
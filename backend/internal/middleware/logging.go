@@ -1,33 +1,50 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
 	"time"
+
+	"goapi/pkg/logger"
 )
 
 // LoggingMiddleware logs HTTP requests
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		
-		// Create a response writer wrapper to capture status code
-		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-		
-		// Call the next handler
-		next.ServeHTTP(wrapped, r)
-		
-		// Log the request
-		duration := time.Since(start)
-		log.Printf(
-			"%s %s %d %v %s",
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			duration,
-			r.RemoteAddr,
-		)
-	})
+func LoggingMiddleware(log logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Create a response writer wrapper to capture status code
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			// Log from a defer so responses aborted with http.ErrAbortHandler are logged too
+			completed := false
+			defer func() {
+				fields := []interface{}{
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", wrapped.statusCode,
+					"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+					"remote_addr", r.RemoteAddr,
+				}
+
+				requestLog := log.WithContext(r.Context())
+				switch {
+				case !completed:
+					requestLog.Error("HTTP request", append(fields, "aborted", true)...)
+				case wrapped.statusCode >= http.StatusInternalServerError:
+					requestLog.Error("HTTP request", fields...)
+				case wrapped.statusCode >= http.StatusBadRequest:
+					requestLog.Warn("HTTP request", fields...)
+				default:
+					requestLog.Info("HTTP request", fields...)
+				}
+			}()
+
+			// Call the next handler
+			next.ServeHTTP(wrapped, r)
+			completed = true
+		})
+	}
 }
 
 // responseWriter wraps http.ResponseWriter to capture status code
@@ -40,3 +57,15 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Flush forwards to the underlying writer so streaming handlers keep working
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
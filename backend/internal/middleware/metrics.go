@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+
+	"goapi/internal/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute labels requests that do not match any route, keeping label cardinality bounded
+const unmatchedRoute = "unmatched"
+
+// statusAborted is recorded for responses aborted with http.ErrAbortHandler, which the client
+// never receives in full whatever status was sent. It is the status nginx uses for requests
+// that ended without a response.
+const statusAborted = 499
+
+// Metrics records request counts, latency and in-flight requests. Requests are labelled by the
+// mux route template rather than the raw path, so /api/users/1 and /api/users/2 share a series.
+// Aborted responses are counted with status 499.
+func Metrics(m *metrics.Metrics, router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done := m.RequestStarted(routeTemplate(router, r), r.Method)
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			// Record from a defer so aborted responses leave the in-flight gauge too
+			completed := false
+			defer func() {
+				if !completed {
+					done(statusAborted)
+					return
+				}
+				done(wrapped.statusCode)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+			completed = true
+		})
+	}
+}
+
+// routeTemplate returns the path template of the route matching r
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return unmatchedRoute
+	}
+
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return template
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"goapi/internal/config"
+)
+
+// NewPanicReporter creates the panic reporter selected by configuration; "none" returns nil
+func NewPanicReporter(cfg config.PanicConfig) (PanicReporter, error) {
+	switch cfg.Reporter {
+	case "none", "":
+		return nil, nil
+	case "file":
+		return NewFilePanicReporter(cfg.FilePath), nil
+	default:
+		return nil, fmt.Errorf("unknown panic reporter %q", cfg.Reporter)
+	}
+}
+
+// FilePanicReporter appends each report as a JSON line to a file, for local development and tests
+type FilePanicReporter struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFilePanicReporter creates a reporter that appends reports to the file at path
+func NewFilePanicReporter(path string) *FilePanicReporter {
+	return &FilePanicReporter{path: path}
+}
+
+// ReportPanic appends the report to the file
+func (r *FilePanicReporter) ReportPanic(ctx context.Context, report PanicReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode panic report: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dir := filepath.Dir(r.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create panic report directory: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open panic report file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write panic report: %w", err)
+	}
+	return nil
+}
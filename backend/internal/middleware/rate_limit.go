@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"goapi/internal/auth"
+	"goapi/internal/models"
+	"goapi/internal/ratelimit"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHeader identifies API clients for rate limiting
+const APIKeyHeader = "X-API-Key"
+
+// RateLimit applies the limiter's per-route token bucket policies. Clients are identified by
+// authenticated user, known API key or IP address. Every limited response carries RateLimit-*
+// headers; refused requests get 429 with Retry-After. Store errors fail open.
+func RateLimit(limiter *ratelimit.Limiter, router *mux.Router, log logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(router, r)
+			policy := limiter.Policy(r.Method, route)
+			if policy == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var userID int
+			if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+				userID = principal.UserID
+			}
+			clientKey := limiter.ClientKey(userID, r.Header.Get(APIKeyHeader), utils.ClientIP(r))
+
+			result, err := limiter.Allow(r.Context(), r.Method+" "+route, clientKey, *policy)
+			if err != nil {
+				log.WithContext(r.Context()).Error("Rate limit check failed", "route", route, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", result.Policy.String())
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Policy.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+				models.WriteTooManyRequestsError(w, "Rate limit exceeded, please retry later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds formats a duration as whole seconds, rounding up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goapi/internal/config"
+	"goapi/internal/ratelimit"
+	"goapi/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRateLimit(t *testing.T) {
+	log, err := logger.New(logger.Options{Level: "error", Output: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		Default: "2/1m",
+		Routes:  []string{"GET /health=unlimited"},
+	}, log)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	router := mux.NewRouter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	router.Handle("/api/users/{id}", ok)
+	router.Handle("/health", ok)
+	handler := RateLimit(limiter, router, log)(router)
+
+	tests := []struct {
+		name          string
+		path          string
+		remoteAddr    string
+		wantStatus    int
+		wantRemaining string
+		wantRetry     bool
+	}{
+		{"first request", "/api/users/1", "192.0.2.1:1234", http.StatusOK, "1", false},
+		{"same route template", "/api/users/2", "192.0.2.1:1234", http.StatusOK, "0", false},
+		{"over the limit", "/api/users/3", "192.0.2.1:1234", http.StatusTooManyRequests, "0", true},
+		{"other client", "/api/users/1", "192.0.2.2:1234", http.StatusOK, "1", false},
+		{"unlimited route", "/health", "192.0.2.1:1234", http.StatusOK, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("RateLimit-Remaining"); got != tt.wantRemaining {
+				t.Fatalf("RateLimit-Remaining = %q, want %q", got, tt.wantRemaining)
+			}
+			if got := rec.Header().Get("Retry-After") != ""; got != tt.wantRetry {
+				t.Fatalf("Retry-After = %q, want set %v", rec.Header().Get("Retry-After"), tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"goapi/internal/auth"
+	"goapi/internal/consistency"
+)
+
+// ReadConsistency starts a read-your-writes session for every request. Authenticated requests
+// share a session per user, so reads that follow a user's write go to the primary even when
+// they arrive in a later request; anonymous requests only see their own writes.
+func ReadConsistency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			key = "user:" + strconv.Itoa(principal.UserID)
+		}
+
+		next.ServeHTTP(w, r.WithContext(consistency.WithSession(r.Context(), key)))
+	})
+}
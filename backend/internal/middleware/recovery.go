@@ -1,22 +1,117 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"time"
 
 	"goapi/internal/models"
+	"goapi/internal/requestid"
+	"goapi/pkg/logger"
 )
 
-// RecoveryMiddleware recovers from panics and returns a 500 error
-func RecoveryMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
-				models.WriteInternalServerError(w, "Internal server error")
-			}
-		}()
-		
-		next.ServeHTTP(w, r)
-	})
+// PanicReport describes a panic recovered while serving a request
+type PanicReport struct {
+	Time      time.Time `json:"time"`
+	Value     string    `json:"value"`
+	Stack     string    `json:"stack"`
+	RequestID string    `json:"request_id,omitempty"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+}
+
+// PanicReporter forwards recovered panics to an error tracker
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, report PanicReport) error
+}
+
+// RecoveryMiddleware recovers from panics, logs them with their stack trace and hands them to
+// reporter, which may be nil. A 500 error is written unless the response had already started.
+// http.ErrAbortHandler is re-panicked so the server aborts the response as intended.
+func RecoveryMiddleware(log logger.Logger, reporter PanicReporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryWriter{ResponseWriter: w}
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				report := PanicReport{
+					Time:      time.Now().UTC(),
+					Value:     fmt.Sprint(recovered),
+					Stack:     string(debug.Stack()),
+					RequestID: requestid.FromContext(r.Context()),
+					Method:    r.Method,
+					Path:      r.URL.Path,
+				}
+				if tp, ok := requestid.TraceParentFromContext(r.Context()); ok {
+					report.TraceID = tp.TraceID
+				}
+
+				requestLog := log.WithContext(r.Context())
+				requestLog.Error("Panic recovered",
+					"error", report.Value,
+					"method", report.Method,
+					"path", report.Path,
+					"response_started", rw.started,
+					"stack", report.Stack,
+				)
+
+				if reporter != nil {
+					if err := reporter.ReportPanic(r.Context(), report); err != nil {
+						requestLog.Error("Failed to report panic", "error", err)
+					}
+				}
+
+				// Headers and part of the body are already on the wire; a second status would be ignored
+				if rw.started {
+					return
+				}
+				models.WriteInternalServerError(rw, "Internal server error")
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// recoveryWriter records whether the response has started
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoveryWriter) WriteHeader(code int) {
+	// Informational responses do not start the final response
+	if code >= 200 {
+		rw.started = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryWriter) Write(p []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer so streaming handlers keep working
+func (rw *recoveryWriter) Flush() {
+	rw.started = true
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Reloadable is a middleware whose implementation can be replaced while requests are served,
+// e.g. when configuration is reloaded. It wraps a single handler.
+type Reloadable struct {
+	next       http.Handler
+	middleware func(http.Handler) http.Handler
+	handler    atomic.Pointer[http.Handler]
+}
+
+// NewReloadable creates a reloadable middleware starting with middleware
+func NewReloadable(middleware func(http.Handler) http.Handler) *Reloadable {
+	return &Reloadable{middleware: middleware}
+}
+
+// Wrap installs the middleware around next
+func (m *Reloadable) Wrap(next http.Handler) http.Handler {
+	m.next = next
+	m.Swap(m.middleware)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*m.handler.Load()).ServeHTTP(w, r)
+	})
+}
+
+// Swap replaces the middleware; requests already running finish with the previous one
+func (m *Reloadable) Swap(middleware func(http.Handler) http.Handler) {
+	m.middleware = middleware
+	if m.next == nil {
+		return
+	}
+
+	handler := middleware(m.next)
+	m.handler.Store(&handler)
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"goapi/internal/requestid"
+	"goapi/pkg/logger"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID accepts or generates an X-Request-ID and W3C trace context for every request.
+// Both are stored in the request context, added to log fields and the ID is echoed in the response.
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if !requestid.IsValid(id) {
+				id = requestid.New()
+			}
+
+			// Prefer the active span so logs carry the same trace ID as exported spans
+			tp, ok := traceParentFromSpan(r.Context())
+			if !ok {
+				tp, ok = requestid.ParseTraceParent(r.Header.Get(requestid.TraceParentHeader))
+			}
+			if !ok {
+				tp = requestid.NewTraceParent()
+			}
+
+			ctx := requestid.WithID(r.Context(), id)
+			ctx = requestid.WithTraceParent(ctx, tp)
+			ctx = logger.ContextWithFields(ctx, "request_id", id, "trace_id", tp.TraceID)
+
+			w.Header().Set(requestid.Header, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// traceParentFromSpan returns the trace context of the span in ctx, if it is valid
+func traceParentFromSpan(ctx context.Context) (requestid.TraceParent, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return requestid.TraceParent{}, false
+	}
+
+	return requestid.TraceParent{
+		TraceID:  sc.TraceID().String(),
+		ParentID: sc.SpanID().String(),
+		Flags:    sc.TraceFlags().String(),
+	}, true
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"goapi/internal/auth"
+	"goapi/internal/models"
+	"goapi/internal/tenant"
+)
+
+// TenantHeader is the development-only header used to pick a tenant without authenticating
+const TenantHeader = "X-Tenant"
+
+// TenantResolver resolves an organization slug or ID to an organization ID
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, ref string) (int, error)
+}
+
+// Tenant scopes the request to an organization. The authenticated principal's organization
+// always wins; when allowHeader is set (development mode only) unauthenticated requests may
+// select a tenant with the X-Tenant header. Principals holding a restricted token, such as an
+// MFA enrollment token, are not scoped at all, so tenant routes reject them.
+func Tenant(resolver TenantResolver, allowHeader bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+				if principal.TokenType != auth.TokenTypeAccess {
+					next.ServeHTTP(w, r)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(tenant.WithOrgID(r.Context(), principal.OrgID)))
+				return
+			}
+
+			ref := r.Header.Get(TenantHeader)
+			if !allowHeader || ref == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			orgID, err := resolver.ResolveTenant(r.Context(), ref)
+			if err != nil {
+				models.WriteValidationError(w, "Unknown tenant")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.WithOrgID(r.Context(), orgID)))
+		})
+	}
+}
+
+// RequireTenant rejects requests that are not scoped to an organization
+func RequireTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := tenant.OrgIDFromContext(r.Context()); !ok {
+			models.WriteUnauthorizedError(w, "Authentication required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
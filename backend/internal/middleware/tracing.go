@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"goapi/internal/tracing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the caller's trace from W3C trace
+// context headers. Spans are named by method and mux route template, e.g. "GET /api/users/{id}".
+func Tracing(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			name := r.Method
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			}
+			if route := routeTemplate(router, r); route != unmatchedRoute {
+				name += " " + route
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+
+			ctx, span := tracing.Tracer().Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			// Annotate from a defer so aborted responses are marked as failed
+			completed := false
+			defer func() {
+				span.SetAttributes(semconv.HTTPResponseStatusCode(wrapped.statusCode))
+				switch {
+				case !completed:
+					span.SetStatus(codes.Error, "response aborted")
+				case wrapped.statusCode >= http.StatusInternalServerError:
+					span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+				}
+			}()
+
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+			completed = true
+		})
+	}
+}
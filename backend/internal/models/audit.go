@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit event types
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditIPLocked        = "ip_locked"
+	AuditAccountUnlocked = "account_unlocked"
+)
+
+// AuditEvent represents a security-relevant event recorded for later review
+type AuditEvent struct {
+	ID        int                    `json:"id" db:"id"`
+	Type      string                 `json:"type" db:"event_type"`
+	UserID    *int                   `json:"user_id,omitempty" db:"user_id"`
+	ActorID   *int                   `json:"actor_id,omitempty" db:"actor_id"`
+	IP        string                 `json:"ip,omitempty" db:"ip"`
+	Details   map[string]interface{} `json:"details,omitempty" db:"details"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+// LoginThrottle tracks failed login attempts for a single account or client key
+type LoginThrottle struct {
+	Key             string
+	Failures        int
+	WindowStartedAt time.Time
+	LastFailureAt   time.Time
+	LockedUntil     *time.Time
+}
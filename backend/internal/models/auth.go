@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+)
+
+// Credentials holds the data needed to authenticate a user
+type Credentials struct {
+	UserID       int
+	OrgID        int
+	Email        string
+	Role         string
+	PasswordHash string
+}
+
+// MFASettings holds a user's TOTP enrollment state
+type MFASettings struct {
+	UserID    int
+	Secret    string
+	EnabledAt *time.Time
+}
+
+// Enabled reports whether the enrollment has been confirmed
+func (m *MFASettings) Enabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
+
+// LoginRequest represents the request payload for logging in.
+// Organization is the slug of the user's organization and may be omitted when the
+// request is already scoped to a tenant.
+type LoginRequest struct {
+	Organization string `json:"organization,omitempty"`
+	Email        string `json:"email" validate:"required,email"`
+	Password     string `json:"password" validate:"required"`
+}
+
+// LoginResponse represents the result of a login step.
+// When MFARequired or MFAEnrollmentRequired is set, MFAToken must be used to
+// complete the second step instead of Token.
+type LoginResponse struct {
+	Token                 string     `json:"token,omitempty"`
+	ExpiresAt             *time.Time `json:"expires_at,omitempty"`
+	MFARequired           bool       `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool       `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string     `json:"mfa_token,omitempty"`
+}
+
+// MFAVerifyRequest represents the request payload for the second login step
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFAEnrollResponse represents the response payload for starting TOTP enrollment
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// MFACodeRequest represents a request payload carrying a TOTP code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFAConfirmResponse represents the response payload for confirming TOTP enrollment
+type MFAConfirmResponse struct {
+	RecoveryCodes []string       `json:"recovery_codes"`
+	Login         *LoginResponse `json:"login,omitempty"`
+}
+
+// TokenRequest represents a request payload carrying an emailed token
+type TokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ForgotPasswordRequest represents the request payload for requesting a password reset
+type ForgotPasswordRequest struct {
+	Organization string `json:"organization,omitempty"`
+	Email        string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest represents the request payload for resetting a password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
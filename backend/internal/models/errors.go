@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"net/http"
+
+	"goapi/internal/requestid"
 )
 
 // APIError represents an API error response
@@ -14,8 +16,9 @@ type APIError struct {
 
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Success bool     `json:"success"`
-	Error   APIError `json:"error"`
+	Success   bool     `json:"success"`
+	Error     APIError `json:"error"`
+	RequestID string   `json:"request_id,omitempty"`
 }
 
 // NewErrorResponse creates a new error response
@@ -36,6 +39,8 @@ func WriteError(w http.ResponseWriter, message string, code int) {
 	w.WriteHeader(code)
 	
 	errorResponse := NewErrorResponse(message, code)
+	// The request ID middleware sets the response header before handlers run
+	errorResponse.RequestID = w.Header().Get(requestid.Header)
 	json.NewEncoder(w).Encode(errorResponse)
 }
 
@@ -58,3 +63,23 @@ func WriteInternalServerError(w http.ResponseWriter, message string) {
 func WriteConflictError(w http.ResponseWriter, message string) {
 	WriteError(w, message, http.StatusConflict)
 }
+
+// WriteUnauthorizedError writes an unauthorized error response
+func WriteUnauthorizedError(w http.ResponseWriter, message string) {
+	WriteError(w, message, http.StatusUnauthorized)
+}
+
+// WriteTooManyRequestsError writes a too many requests error response
+func WriteTooManyRequestsError(w http.ResponseWriter, message string) {
+	WriteError(w, message, http.StatusTooManyRequests)
+}
+
+// WriteForbiddenError writes a forbidden error response
+func WriteForbiddenError(w http.ResponseWriter, message string) {
+	WriteError(w, message, http.StatusForbidden)
+}
+
+// WritePayloadTooLargeError writes a payload too large error response
+func WritePayloadTooLargeError(w http.ResponseWriter, message string) {
+	WriteError(w, message, http.StatusRequestEntityTooLarge)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Invitation represents a pending or resolved invitation to join an organization
+type Invitation struct {
+	ID         int        `json:"id" db:"id"`
+	OrgID      int        `json:"org_id" db:"org_id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	TokenID    string     `json:"-" db:"token_id"`
+	InvitedBy  *int       `json:"invited_by,omitempty" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsPending reports whether the invitation can still be accepted
+func (i *Invitation) IsPending() bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && time.Now().Before(i.ExpiresAt)
+}
+
+// CreateInvitationRequest represents the request payload for inviting a user
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role,omitempty" validate:"omitempty,oneof=user admin superadmin"`
+}
+
+// CreateInvitationResponse represents a newly created invitation together with its token
+type CreateInvitationResponse struct {
+	Invitation
+	Token string `json:"token"`
+}
+
+// AcceptInvitationRequest represents the request payload for accepting an invitation
+type AcceptInvitationRequest struct {
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Password string `json:"password" validate:"required,min=8"`
+}
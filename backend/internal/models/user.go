@@ -7,16 +7,42 @@ import (
 // User represents a user in our database
 type User struct {
 	ID        int       `json:"id" db:"id"`
+	OrgID     int       `json:"org_id" db:"org_id"`
 	Name      string    `json:"name" db:"name"`
 	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+}
+
+// UserListVersion identifies a state of an organization's user list. Creating or deleting a
+// user changes Count and every update advances LastModified, so any change to the list changes
+// the version. LastModified is nil while the list is empty.
+type UserListVersion struct {
+	Count        int
+	LastModified *time.Time
+}
+
+// User roles
+const (
+	RoleUser       = "user"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "superadmin"
+)
+
+// IsValidRole reports whether role is a known user role
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin || role == RoleSuperAdmin
 }
 
 // CreateUserRequest represents the request payload for creating a user
 type CreateUserRequest struct {
-	Name  string `json:"name" validate:"required,min=2,max=100"`
-	Email string `json:"email" validate:"required,email"`
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password,omitempty" validate:"omitempty,min=8"`
+	Role     string `json:"role,omitempty" validate:"omitempty,oneof=user admin superadmin"`
 }
 
 // UpdateUserRequest represents the request payload for updating a user
@@ -28,19 +54,27 @@ type UpdateUserRequest struct {
 // UserResponse represents the response payload for user operations
 type UserResponse struct {
 	ID        int       `json:"id"`
+	OrgID     int       `json:"org_id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
 
 // ToResponse converts a User model to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
 		ID:        u.ID,
+		OrgID:     u.OrgID,
 		Name:      u.Name,
 		Email:     u.Email,
+		Role:      u.Role,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+
+		EmailVerifiedAt: u.EmailVerifiedAt,
 	}
 }
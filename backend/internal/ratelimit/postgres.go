@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that all replicas share limits
+type PostgresStore struct {
+	repo *database.RateLimitRepository
+}
+
+// NewPostgresStore creates a bucket store backed by Postgres
+func NewPostgresStore(repo *database.RateLimitRepository) *PostgresStore {
+	return &PostgresStore{repo: repo}
+}
+
+// Take removes a token from the bucket for key in a single atomic statement
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (bool, float64, error) {
+	return s.repo.Take(ctx, key, float64(policy.Limit), policy.rate())
+}
+
+// Sweep deletes buckets that have not been used for longer than maxIdle
+func (s *PostgresStore) Sweep(ctx context.Context, maxIdle time.Duration) error {
+	return s.repo.DeleteIdle(ctx, maxIdle)
+}
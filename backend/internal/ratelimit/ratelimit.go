@@ -0,0 +1,254 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"goapi/internal/config"
+	"goapi/pkg/logger"
+)
+
+// sweepInterval is how often idle buckets are removed from the store
+const sweepInterval = time.Minute
+
+// Policy is a token bucket allowing Limit requests per Window, refilled continuously
+type Policy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// rate returns the number of tokens added per second
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+// String formats the policy for the RateLimit-Policy header, e.g. "10;w=60"
+func (p Policy) String() string {
+	return strconv.Itoa(p.Limit) + ";w=" + strconv.Itoa(int(p.Window.Seconds()))
+}
+
+// ParsePolicy parses a policy written as "<limit>/<window>", e.g. "10/1m".
+// It returns nil for "unlimited".
+func ParsePolicy(s string) (*Policy, error) {
+	s = strings.TrimSpace(s)
+	if s == "unlimited" {
+		return nil, nil
+	}
+
+	limit, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid rate limit %q: expected <limit>/<window>", s)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid rate limit %q: limit must be a positive integer", s)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d < time.Second {
+		return nil, fmt.Errorf("invalid rate limit %q: window must be a duration of at least 1s", s)
+	}
+
+	return &Policy{Limit: n, Window: d}, nil
+}
+
+// Store holds token buckets. Implementations must take tokens atomically so that
+// concurrent requests, including ones served by other replicas, share a bucket.
+type Store interface {
+	// Take removes a token from the bucket for key, creating a full bucket when there is none.
+	// It reports whether a token was available and how many tokens are left.
+	Take(ctx context.Context, key string, policy Policy) (allowed bool, tokens float64, err error)
+
+	// Sweep removes buckets idle for longer than maxIdle, which have refilled completely
+	Sweep(ctx context.Context, maxIdle time.Duration) error
+}
+
+// Result describes the state of a bucket after a request
+type Result struct {
+	Allowed    bool
+	Policy     Policy
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter applies per-route policies to client keys
+type Limiter struct {
+	store     Store
+	policies  atomic.Pointer[policies]
+	lastSweep atomic.Int64
+	log       logger.Logger
+}
+
+// policies holds the parsed configuration, replaced as a whole when it is reloaded
+type policies struct {
+	defaultPolicy *Policy
+	routes        map[string]*Policy
+	apiKeys       map[string]bool
+	maxWindow     time.Duration
+}
+
+// NewLimiter creates a limiter from configuration, rejecting malformed policies
+func NewLimiter(store Store, cfg config.RateLimitConfig, log logger.Logger) (*Limiter, error) {
+	l := &Limiter{store: store, log: log}
+	if err := l.Update(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update replaces the policies and API keys. On error the current ones are kept.
+// Buckets are kept as well, so clients keep the tokens they have left.
+func (l *Limiter) Update(cfg config.RateLimitConfig) error {
+	p, err := parsePolicies(cfg)
+	if err != nil {
+		return err
+	}
+	l.policies.Store(p)
+	return nil
+}
+
+// parsePolicies parses the default and per-route policies and hashes the API keys
+func parsePolicies(cfg config.RateLimitConfig) (*policies, error) {
+	defaultPolicy, err := ParsePolicy(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_DEFAULT: %w", err)
+	}
+
+	p := &policies{
+		defaultPolicy: defaultPolicy,
+		routes:        make(map[string]*Policy),
+		apiKeys:       make(map[string]bool),
+	}
+	p.trackWindow(defaultPolicy)
+
+	for _, entry := range cfg.Routes {
+		route, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("RATE_LIMIT_ROUTES: invalid entry %q: expected \"<METHOD> <path>=<limit>/<window>\"", entry)
+		}
+
+		route = normalizeRoute(route)
+		if _, _, ok := strings.Cut(route, " "); !ok {
+			return nil, fmt.Errorf("RATE_LIMIT_ROUTES: invalid route %q: expected \"<METHOD> <path>\"", route)
+		}
+
+		policy, err := ParsePolicy(value)
+		if err != nil {
+			return nil, fmt.Errorf("RATE_LIMIT_ROUTES: %s: %w", route, err)
+		}
+		p.routes[route] = policy
+		p.trackWindow(policy)
+	}
+
+	for _, key := range cfg.APIKeys {
+		p.apiKeys[hashKey(key)] = true
+	}
+
+	return p, nil
+}
+
+// Policy returns the policy for a route given as "<METHOD> <path template>".
+// It returns nil when the route is unlimited.
+func (l *Limiter) Policy(method, route string) *Policy {
+	p := l.policies.Load()
+	if policy, ok := p.routes[normalizeRoute(method+" "+route)]; ok {
+		return policy
+	}
+	return p.defaultPolicy
+}
+
+// ClientKey identifies the caller by authenticated user, known API key or client IP, in that order
+func (l *Limiter) ClientKey(userID int, apiKey, ip string) string {
+	if userID > 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+
+	if apiKey != "" {
+		// Unknown keys fall back to the IP so that inventing keys cannot bypass the limit
+		if hash := hashKey(apiKey); l.policies.Load().apiKeys[hash] {
+			return "apikey:" + hash[:16]
+		}
+	}
+
+	return "ip:" + ip
+}
+
+// Allow takes a token for the client key from the route's bucket
+func (l *Limiter) Allow(ctx context.Context, route, clientKey string, policy Policy) (Result, error) {
+	l.maybeSweep()
+
+	allowed, tokens, err := l.store.Take(ctx, route+"|"+clientKey, policy)
+	if err != nil {
+		return Result{}, err
+	}
+
+	rate := policy.rate()
+	result := Result{
+		Allowed:   allowed,
+		Policy:    policy,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(policy.Limit) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return result, nil
+}
+
+// maybeSweep removes idle buckets in the background at most once per sweep interval
+func (l *Limiter) maybeSweep() {
+	now := time.Now().UnixNano()
+	last := l.lastSweep.Load()
+	if now-last < int64(sweepInterval) || !l.lastSweep.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := l.store.Sweep(ctx, l.policies.Load().maxWindow); err != nil {
+			l.log.Warn("Failed to sweep rate limit buckets", "error", err)
+		}
+	}()
+}
+
+// trackWindow records the longest window, after which any idle bucket is full again
+func (p *policies) trackWindow(policy *Policy) {
+	if policy != nil && policy.Window > p.maxWindow {
+		p.maxWindow = policy.Window
+	}
+}
+
+// normalizeRoute upper-cases the method and collapses surrounding whitespace
+func normalizeRoute(route string) string {
+	method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+	if !ok {
+		return strings.TrimSpace(route)
+	}
+	return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+}
+
+// hashKey hashes an API key so raw keys are neither kept in memory nor used as bucket keys
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// seconds converts fractional seconds to a duration
+func seconds(s float64) time.Duration {
+	if s < 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
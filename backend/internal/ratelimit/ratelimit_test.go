@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"goapi/internal/config"
+	"goapi/pkg/logger"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    *Policy
+		wantErr bool
+	}{
+		{in: "10/1m", want: &Policy{Limit: 10, Window: time.Minute}},
+		{in: " 100/1h ", want: &Policy{Limit: 100, Window: time.Hour}},
+		{in: "unlimited", want: nil},
+		{in: "10", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "ten/1m", wantErr: true},
+		{in: "10/500ms", wantErr: true},
+		{in: "10/minute", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePolicy(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("ParsePolicy(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	policy := Policy{Limit: 3, Window: 3 * time.Second}
+
+	// take advances the clock and takes a token
+	type take struct {
+		advance     time.Duration
+		wantAllowed bool
+		wantTokens  float64
+	}
+
+	tests := []struct {
+		name  string
+		steps []take
+	}{
+		{"a new bucket starts full", []take{{0, true, 2}, {0, true, 1}, {0, true, 0}, {0, false, 0}}},
+		{"tokens refill over the window", []take{{0, true, 2}, {0, true, 1}, {0, true, 0}, {500 * time.Millisecond, false, 0.5}, {500 * time.Millisecond, true, 0}}},
+		{"refills stop at the limit", []take{{0, true, 2}, {time.Hour, true, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			store := NewMemoryStore()
+			store.now = func() time.Time { return now }
+
+			for i, step := range tt.steps {
+				now = now.Add(step.advance)
+				allowed, tokens, err := store.Take(context.Background(), "key", policy)
+				if err != nil {
+					t.Fatalf("Take: %v", err)
+				}
+				if allowed != step.wantAllowed || tokens != step.wantTokens {
+					t.Fatalf("step %d: Take = (%v, %v), want (%v, %v)", i+1, allowed, tokens, step.wantAllowed, step.wantTokens)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreConcurrentTakes(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Limit: 5, Window: time.Hour}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := store.Take(context.Background(), "key", policy)
+			if err != nil {
+				t.Errorf("Take: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != policy.Limit {
+		t.Fatalf("%d requests allowed, want %d", allowed, policy.Limit)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	log, err := logger.New(logger.Options{Level: "error", Output: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	limiter, err := NewLimiter(NewMemoryStore(), config.RateLimitConfig{
+		Default: "100/1m",
+		Routes:  []string{"post /api/auth/login = 5/1m", "GET /health=unlimited"},
+		APIKeys: []string{"known-key"},
+	}, log)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	t.Run("policies", func(t *testing.T) {
+		tests := []struct {
+			method, route string
+			want          *Policy
+		}{
+			{"POST", "/api/auth/login", &Policy{Limit: 5, Window: time.Minute}},
+			{"post", "/api/auth/login", &Policy{Limit: 5, Window: time.Minute}},
+			{"GET", "/api/auth/login", &Policy{Limit: 100, Window: time.Minute}},
+			{"GET", "/health", nil},
+		}
+		for _, tt := range tests {
+			got := limiter.Policy(tt.method, tt.route)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("Policy(%s %s) = %+v, want %+v", tt.method, tt.route, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("client keys", func(t *testing.T) {
+		known := "apikey:" + hashKey("known-key")[:16]
+		tests := []struct {
+			name   string
+			userID int
+			apiKey string
+			want   string
+		}{
+			{"authenticated user", 7, "known-key", "user:7"},
+			{"known API key", 0, "known-key", known},
+			{"unknown API key", 0, "invented-key", "ip:192.0.2.1"},
+			{"anonymous", 0, "", "ip:192.0.2.1"},
+		}
+		for _, tt := range tests {
+			if got := limiter.ClientKey(tt.userID, tt.apiKey, "192.0.2.1"); got != tt.want {
+				t.Fatalf("%s: ClientKey = %q, want %q", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("invalid updates keep the current policies", func(t *testing.T) {
+		if err := limiter.Update(config.RateLimitConfig{Default: "100/1m", Routes: []string{"/api/users=1/1m"}}); err == nil {
+			t.Fatal("Update accepted a route without a method")
+		}
+		if got := limiter.Policy("POST", "/api/auth/login"); got == nil || got.Limit != 5 {
+			t.Fatalf("policy changed to %+v", got)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// Header carries the request ID from clients and back in responses
+	Header = "X-Request-ID"
+	// TraceParentHeader is the W3C Trace Context header
+	TraceParentHeader = "traceparent"
+
+	maxLength = 128
+)
+
+type idKey struct{}
+
+type traceKey struct{}
+
+// TraceParent is a parsed W3C traceparent header
+type TraceParent struct {
+	TraceID  string
+	ParentID string
+	Flags    string
+}
+
+// String formats the trace parent as a version 00 traceparent header value
+func (t TraceParent) String() string {
+	return "00-" + t.TraceID + "-" + t.ParentID + "-" + t.Flags
+}
+
+// WithID returns a copy of ctx carrying the request ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "" when there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// WithTraceParent returns a copy of ctx carrying the trace parent
+func WithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	return context.WithValue(ctx, traceKey{}, tp)
+}
+
+// TraceParentFromContext returns the trace parent carried by ctx, if any
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	tp, ok := ctx.Value(traceKey{}).(TraceParent)
+	return tp, ok
+}
+
+// New generates a random request ID
+func New() string {
+	return randomHex(16)
+}
+
+// NewTraceParent starts a new sampled trace
+func NewTraceParent() TraceParent {
+	return TraceParent{TraceID: randomHex(16), ParentID: randomHex(8), Flags: "01"}
+}
+
+// IsValid reports whether a client-supplied request ID is safe to log and echo back
+func IsValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("-_.:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceParent parses a W3C traceparent header, rejecting malformed or all-zero IDs
+func ParseTraceParent(header string) (TraceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return TraceParent{}, false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceParent{}, false
+	}
+
+	if !isHex(traceID, 32) || !isHex(parentID, 16) || !isHex(flags, 2) {
+		return TraceParent{}, false
+	}
+
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return TraceParent{}, false
+	}
+
+	return TraceParent{TraceID: traceID, ParentID: parentID, Flags: flags}, true
+}
+
+// isHex reports whether s is exactly n lowercase hex digits
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("requestid: failed to read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
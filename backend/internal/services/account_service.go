@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"goapi/internal/auth"
+	"goapi/internal/config"
+	"goapi/internal/database"
+	"goapi/internal/mailer"
+	"goapi/internal/models"
+)
+
+var (
+	// ErrInvalidAccountToken is returned when a verification or reset token is invalid, expired or already used
+	ErrInvalidAccountToken = errors.New("invalid account token")
+)
+
+// AccountService handles email verification and password reset flows
+type AccountService struct {
+	userRepo UserStore
+	authRepo *database.AuthRepository
+	tokens   *auth.TokenManager
+	mailer   mailer.Mailer
+	authCfg  config.AuthConfig
+	mailCfg  config.MailConfig
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(userRepo UserStore, authRepo *database.AuthRepository, tokens *auth.TokenManager, m mailer.Mailer, authCfg config.AuthConfig, mailCfg config.MailConfig) *AccountService {
+	return &AccountService{
+		userRepo: userRepo,
+		authRepo: authRepo,
+		tokens:   tokens,
+		mailer:   m,
+		authCfg:  authCfg,
+		mailCfg:  mailCfg,
+	}
+}
+
+// SendVerificationEmail emails the user a link to verify their current address
+func (s *AccountService) SendVerificationEmail(ctx context.Context, orgID, userID int) error {
+	user, err := s.userRepo.GetByID(ctx, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	ttl := time.Duration(s.authCfg.VerificationTokenTTL) * time.Minute
+	token, _, err := issueSingleUseToken(s.tokens, auth.Claims{UserID: user.ID, OrgID: user.OrgID, Email: user.Email, Type: auth.TokenTypeVerifyEmail}, ttl)
+	if err != nil {
+		return err
+	}
+
+	return s.send(user, "Verify your email address", "verify_email", "/verify-email", token, ttl)
+}
+
+// VerifyEmail marks the email in the token as verified if it still belongs to the user
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.tokens.Parse(token, auth.TokenTypeVerifyEmail)
+	if err != nil || claims.ID == "" {
+		return ErrInvalidAccountToken
+	}
+
+	if err := s.authRepo.VerifyEmail(ctx, claims.ID, claims.Expiry(), claims.UserID, claims.Email); err != nil {
+		return accountTokenError(err)
+	}
+
+	return nil
+}
+
+// RequestPasswordReset emails a reset link if an account exists for the email.
+// A missing account is not reported so the endpoint cannot be used to discover users.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, orgID int, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, orgID, email)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	ttl := time.Duration(s.authCfg.PasswordResetTTL) * time.Minute
+	token, _, err := issueSingleUseToken(s.tokens, auth.Claims{UserID: user.ID, OrgID: user.OrgID, Email: user.Email, Type: auth.TokenTypePasswordReset}, ttl)
+	if err != nil {
+		return err
+	}
+
+	return s.send(user, "Reset your password", "password_reset", "/reset-password", token, ttl)
+}
+
+// ResetPassword sets a new password using a password reset token
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	claims, err := s.tokens.Parse(token, auth.TokenTypePasswordReset)
+	if err != nil || claims.ID == "" {
+		return ErrInvalidAccountToken
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.authRepo.ResetPassword(ctx, claims.ID, claims.Expiry(), claims.UserID, hash); err != nil {
+		return accountTokenError(err)
+	}
+
+	return nil
+}
+
+// send renders the named template with a link to path carrying the token and sends it to the user
+func (s *AccountService) send(user *models.User, subject, template, path, token string, ttl time.Duration) error {
+	return sendTemplatedEmail(s.mailer, s.mailCfg, user.Email, subject, template, map[string]interface{}{
+		"Name":      user.Name,
+		"URL":       tokenLink(s.mailCfg, path, token),
+		"ExpiresIn": formatDuration(ttl),
+	})
+}
+
+// issueSingleUseToken issues a token carrying a fresh identifier that can be consumed once
+func issueSingleUseToken(tokens *auth.TokenManager, claims auth.Claims, ttl time.Duration) (string, *auth.Claims, error) {
+	id, err := auth.NewTokenID()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate token id: %w", err)
+	}
+	claims.ID = id
+
+	token, issued, err := tokens.Issue(claims, ttl)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to issue token: %w", err)
+	}
+
+	return token, issued, nil
+}
+
+// tokenLink returns the frontend URL for path carrying the token as a query parameter
+func tokenLink(cfg config.MailConfig, path, token string) string {
+	return cfg.BaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendTemplatedEmail renders the named template with data and sends it to the recipient
+func sendTemplatedEmail(m mailer.Mailer, cfg config.MailConfig, to, subject, template string, data map[string]interface{}) error {
+	msg, err := mailer.NewTemplatedMessage(to, subject, template, data)
+	if err != nil {
+		return err
+	}
+	msg.From = cfg.From
+
+	if err := m.Send(msg); err != nil {
+		return fmt.Errorf("failed to send %s email: %w", template, err)
+	}
+
+	return nil
+}
+
+// accountTokenError maps repository errors for consumed or stale tokens to ErrInvalidAccountToken
+func accountTokenError(err error) error {
+	if errors.Is(err, database.ErrTokenUsed) || errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidAccountToken
+	}
+	return err
+}
+
+// formatDuration renders a token lifetime in whole hours or minutes for email bodies
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return pluralize(int(d/time.Hour), "hour")
+	}
+	return pluralize(int(d/time.Minute), "minute")
+}
+
+// pluralize formats a count with a singular or plural unit
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
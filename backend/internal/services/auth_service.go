@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goapi/internal/auth"
+	"goapi/internal/config"
+	"goapi/internal/database"
+	"goapi/internal/models"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the email or password is wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidMFACode is returned when a TOTP or recovery code does not match
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+	// ErrMFANotEnrolled is returned when an MFA operation needs an enrollment that does not exist
+	ErrMFANotEnrolled = errors.New("mfa not enrolled")
+	// ErrMFAAlreadyEnabled is returned when enrolling a user whose MFA is already confirmed
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+)
+
+// mfaTokenTTL bounds how long a user has to complete the second login step
+const mfaTokenTTL = 5 * time.Minute
+
+// qrCodeSize is the width and height in pixels of rendered enrollment QR codes
+const qrCodeSize = 256
+
+// AuthService handles login and multi-factor authentication
+type AuthService struct {
+	userRepo UserStore
+	authRepo *database.AuthRepository
+	tokens   *auth.TokenManager
+	guard    *LoginGuard
+	cfg      config.AuthConfig
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(userRepo UserStore, authRepo *database.AuthRepository, tokens *auth.TokenManager, guard *LoginGuard, cfg config.AuthConfig) *AuthService {
+	return &AuthService{
+		userRepo: userRepo,
+		authRepo: authRepo,
+		tokens:   tokens,
+		guard:    guard,
+		cfg:      cfg,
+	}
+}
+
+// Login verifies the password of a user in the organization and either issues an access
+// token or starts the MFA step. Attempts are throttled per account and per client IP.
+func (s *AuthService) Login(ctx context.Context, orgID int, req models.LoginRequest, ip string) (*models.LoginResponse, error) {
+	keys := []string{AccountKey(orgID, req.Email), IPKey(ip)}
+	if err := s.guard.Reserve(ctx, keys...); err != nil {
+		return nil, err
+	}
+
+	// Only an unknown email counts as a failure; a failed lookup says nothing about the password
+	creds, err := s.authRepo.GetCredentialsByEmail(ctx, orgID, req.Email)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		s.guard.release(ctx, keys...)
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	if err != nil || !auth.CheckPassword(creds.PasswordHash, req.Password) {
+		var userID *int
+		if creds != nil {
+			userID = &creds.UserID
+		}
+		if err := s.guard.RecordFailure(ctx, userID, ip, keys...); err != nil {
+			return nil, fmt.Errorf("failed to record login failure: %w", err)
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.guard.RecordSuccess(ctx, keys...); err != nil {
+		return nil, fmt.Errorf("failed to reset login failures: %w", err)
+	}
+
+	mfa, err := s.authRepo.GetMFA(ctx, creds.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mfa settings: %w", err)
+	}
+
+	if mfa.Enabled() {
+		token, _, err := s.tokens.Issue(auth.Claims{UserID: creds.UserID, OrgID: creds.OrgID, Role: creds.Role, Type: auth.TokenTypeMFAChallenge}, mfaTokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue mfa token: %w", err)
+		}
+		return &models.LoginResponse{MFARequired: true, MFAToken: token}, nil
+	}
+
+	if s.mfaRequired(creds.Role) {
+		token, _, err := s.tokens.Issue(auth.Claims{UserID: creds.UserID, OrgID: creds.OrgID, Role: creds.Role, Type: auth.TokenTypeMFAEnrollment}, mfaTokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue enrollment token: %w", err)
+		}
+		return &models.LoginResponse{MFAEnrollmentRequired: true, MFAToken: token}, nil
+	}
+
+	return s.issueAccessToken(creds.UserID, creds.OrgID, creds.Role)
+}
+
+// VerifyMFA completes a login by checking a TOTP or recovery code against an MFA challenge token.
+// Attempts are throttled per user and per client IP.
+func (s *AuthService) VerifyMFA(ctx context.Context, req models.MFAVerifyRequest, ip string) (*models.LoginResponse, error) {
+	claims, err := s.tokens.Parse(req.MFAToken, auth.TokenTypeMFAChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{MFAKey(claims.UserID), IPKey(ip)}
+	if err := s.guard.Reserve(ctx, keys...); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.authRepo.GetMFA(ctx, claims.UserID)
+	if err != nil {
+		s.guard.release(ctx, keys...)
+		return nil, fmt.Errorf("failed to load mfa settings: %w", err)
+	}
+	if !mfa.Enabled() {
+		s.guard.release(ctx, keys...)
+		return nil, ErrMFANotEnrolled
+	}
+
+	accepted, err := s.useTOTP(ctx, mfa, req.Code)
+	if err != nil {
+		s.guard.release(ctx, keys...)
+		return nil, err
+	}
+
+	if !accepted {
+		used, err := s.authRepo.UseRecoveryCode(ctx, claims.UserID, auth.HashRecoveryCode(req.Code))
+		if err != nil {
+			s.guard.release(ctx, keys...)
+			return nil, fmt.Errorf("failed to check recovery code: %w", err)
+		}
+		if !used {
+			if err := s.guard.RecordFailure(ctx, &claims.UserID, ip, keys...); err != nil {
+				return nil, fmt.Errorf("failed to record mfa failure: %w", err)
+			}
+			return nil, ErrInvalidMFACode
+		}
+	}
+
+	if err := s.guard.RecordSuccess(ctx, keys...); err != nil {
+		return nil, fmt.Errorf("failed to reset mfa failures: %w", err)
+	}
+
+	return s.issueAccessToken(claims.UserID, claims.OrgID, claims.Role)
+}
+
+// UnlockUser clears a user's login and MFA lockouts on behalf of an admin in the same organization
+func (s *AuthService) UnlockUser(ctx context.Context, orgID, userID, actorID int) error {
+	user, err := s.userRepo.GetByID(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.guard.Unlock(ctx, user.ID, actorID, AccountKey(user.OrgID, user.Email), MFAKey(user.ID))
+}
+
+// EnrollMFA generates a new pending TOTP secret for the principal
+func (s *AuthService) EnrollMFA(ctx context.Context, principal *auth.Principal) (*models.MFAEnrollResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, principal.OrgID, principal.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	key, err := auth.GenerateTOTPKey(s.cfg.MFAIssuer, user.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	if err := s.authRepo.SavePendingMFA(ctx, user.ID, key.Secret()); err != nil {
+		if errors.Is(err, database.ErrMFAEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &models.MFAEnrollResponse{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+	}, nil
+}
+
+// MFAQRCode renders the principal's pending enrollment as a PNG QR code
+func (s *AuthService) MFAQRCode(ctx context.Context, principal *auth.Principal) ([]byte, error) {
+	user, err := s.userRepo.GetByID(ctx, principal.OrgID, principal.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	mfa, err := s.authRepo.GetMFA(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mfa settings: %w", err)
+	}
+	if mfa == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := auth.TOTPKeyFromSecret(s.cfg.MFAIssuer, user.Email, mfa.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build totp key: %w", err)
+	}
+
+	return auth.TOTPQRCode(key, qrCodeSize)
+}
+
+// ConfirmMFA enables a pending enrollment once the user proves possession with a valid code.
+// Callers holding an enrollment token receive an access token alongside the recovery codes.
+func (s *AuthService) ConfirmMFA(ctx context.Context, principal *auth.Principal, code string) (*models.MFAConfirmResponse, error) {
+	mfa, err := s.authRepo.GetMFA(ctx, principal.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mfa settings: %w", err)
+	}
+	if mfa == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	accepted, err := s.useTOTP(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authRepo.EnableMFA(ctx, principal.UserID, hashes); err != nil {
+		return nil, err
+	}
+
+	response := &models.MFAConfirmResponse{RecoveryCodes: codes}
+	if principal.TokenType == auth.TokenTypeMFAEnrollment {
+		response.Login, err = s.issueAccessToken(principal.UserID, principal.OrgID, principal.Role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	mfa, err := s.authRepo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mfa settings: %w", err)
+	}
+	if !mfa.Enabled() {
+		return nil, ErrMFANotEnrolled
+	}
+
+	accepted, err := s.useTOTP(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useTOTP checks a TOTP code and records its time step, rejecting a valid code whose step, or a
+// later one, was already used so that an observed code cannot be replayed
+func (s *AuthService) useTOTP(ctx context.Context, mfa *models.MFASettings, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(mfa.Secret, code)
+	if !ok {
+		return false, nil
+	}
+
+	used, err := s.authRepo.UseTOTPStep(ctx, mfa.UserID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to check totp code: %w", err)
+	}
+	return used, nil
+}
+
+// issueAccessToken issues a full access token for the user
+func (s *AuthService) issueAccessToken(userID, orgID int, role string) (*models.LoginResponse, error) {
+	ttl := time.Duration(s.cfg.TokenTTL) * time.Minute
+	token, claims, err := s.tokens.Issue(auth.Claims{UserID: userID, OrgID: orgID, Role: role, Type: auth.TokenTypeAccess}, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue access token: %w", err)
+	}
+
+	expiresAt := claims.Expiry()
+	return &models.LoginResponse{Token: token, ExpiresAt: &expiresAt}, nil
+}
+
+// mfaRequired reports whether the role must complete MFA to log in
+func (s *AuthService) mfaRequired(role string) bool {
+	for _, r := range s.cfg.MFARequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// generateRecoveryCodes returns fresh recovery codes together with their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goapi/internal/auth"
+	"goapi/internal/config"
+	"goapi/internal/database"
+	"goapi/internal/mailer"
+	"goapi/internal/models"
+	"goapi/pkg/logger"
+)
+
+var (
+	// ErrInvitationNotFound is returned when a pending invitation does not exist
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvalidInvitation is returned when an invitation token is invalid, expired, revoked or already accepted
+	ErrInvalidInvitation = errors.New("invalid invitation")
+)
+
+// InvitationService handles inviting users into an organization
+type InvitationService struct {
+	inviteRepo  *database.InvitationRepository
+	orgRepo     *database.OrganizationRepository
+	userRepo    UserStore
+	userService *UserService
+	tokens      *auth.TokenManager
+	mailer      mailer.Mailer
+	authCfg     config.AuthConfig
+	mailCfg     config.MailConfig
+	log         logger.Logger
+}
+
+// NewInvitationService creates a new invitation service
+func NewInvitationService(inviteRepo *database.InvitationRepository, orgRepo *database.OrganizationRepository, userRepo UserStore, userService *UserService, tokens *auth.TokenManager, m mailer.Mailer, authCfg config.AuthConfig, mailCfg config.MailConfig, log logger.Logger) *InvitationService {
+	return &InvitationService{
+		inviteRepo:  inviteRepo,
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		userService: userService,
+		tokens:      tokens,
+		mailer:      m,
+		authCfg:     authCfg,
+		mailCfg:     mailCfg,
+		log:         log,
+	}
+}
+
+// CreateInvitation invites an email address into the organization and emails it a signed token.
+// A previous pending invitation for the same address is revoked.
+func (s *InvitationService) CreateInvitation(ctx context.Context, orgID, inviterID int, req models.CreateInvitationRequest) (*models.CreateInvitationResponse, error) {
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+
+	// Inviting an existing member is rejected the same way as creating a duplicate user
+	if _, err := s.userRepo.GetByEmail(ctx, orgID, req.Email); err == nil {
+		return nil, ErrEmailExists
+	}
+
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	ttl := time.Duration(s.authCfg.InvitationTTL) * time.Minute
+	token, claims, err := issueSingleUseToken(s.tokens, auth.Claims{OrgID: orgID, Email: req.Email, Role: req.Role, Type: auth.TokenTypeInvitation}, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.inviteRepo.Create(ctx, models.Invitation{
+		OrgID:     orgID,
+		Email:     req.Email,
+		Role:      req.Role,
+		TokenID:   claims.ID,
+		InvitedBy: &inviterID,
+		ExpiresAt: claims.Expiry(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is returned to the inviter, so a failed email does not fail the invitation
+	err = sendTemplatedEmail(s.mailer, s.mailCfg, req.Email, "You're invited to "+org.Name, "invitation", map[string]interface{}{
+		"Organization": org.Name,
+		"URL":          tokenLink(s.mailCfg, "/accept-invitation", token),
+		"ExpiresIn":    formatDuration(ttl),
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to send invitation", "org_id", orgID, "invitation_id", invitation.ID, "error", err)
+	}
+
+	return &models.CreateInvitationResponse{Invitation: *invitation, Token: token}, nil
+}
+
+// GetPendingInvitations retrieves the organization's invitations that can still be accepted
+func (s *InvitationService) GetPendingInvitations(ctx context.Context, orgID int) ([]models.Invitation, error) {
+	invitations, err := s.inviteRepo.GetPending(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+
+	if invitations == nil {
+		invitations = []models.Invitation{}
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation revokes a pending invitation in the organization
+func (s *InvitationService) RevokeInvitation(ctx context.Context, orgID, id int) error {
+	if err := s.inviteRepo.Revoke(ctx, orgID, id); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AcceptInvitation creates the invited user through the user service with a verified email,
+// claiming the invitation in the same transaction so each invitation creates at most one user
+func (s *InvitationService) AcceptInvitation(ctx context.Context, token string, req models.AcceptInvitationRequest) (*models.UserResponse, error) {
+	claims, err := s.tokens.Parse(token, auth.TokenTypeInvitation)
+	if err != nil || claims.ID == "" {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitation, err := s.inviteRepo.GetByTokenID(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+
+	if !invitation.IsPending() {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := s.userService.CreateInvitedUser(ctx, invitation.OrgID, models.CreateUserRequest{
+		Name:     req.Name,
+		Email:    invitation.Email,
+		Password: req.Password,
+		Role:     invitation.Role,
+	}, s.inviteRepo.Claim(invitation.ID))
+	if err != nil {
+		// The invitation was accepted, revoked or expired after it was read
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
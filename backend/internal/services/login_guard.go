@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/internal/config"
+	"goapi/internal/database"
+	"goapi/internal/models"
+	"goapi/pkg/logger"
+)
+
+// ThrottledError is returned when a login attempt is refused because of earlier failures
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("login locked, retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("login throttled, retry after %s", e.RetryAfter)
+}
+
+// LoginGuard tracks failed login attempts per account and per client IP, applying
+// progressive delays and temporary lockouts
+type LoginGuard struct {
+	repo  *database.LockoutRepository
+	audit *database.AuditRepository
+	cfg   config.LockoutConfig
+	log   logger.Logger
+}
+
+// NewLoginGuard creates a new login guard
+func NewLoginGuard(repo *database.LockoutRepository, audit *database.AuditRepository, cfg config.LockoutConfig, log logger.Logger) *LoginGuard {
+	return &LoginGuard{
+		repo:  repo,
+		audit: audit,
+		cfg:   cfg,
+		log:   log,
+	}
+}
+
+// AccountKey returns the throttle key for password attempts against an email in an organization
+func AccountKey(orgID int, email string) string {
+	return "account:" + strconv.Itoa(orgID) + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// MFAKey returns the throttle key for second-factor attempts against a user
+func MFAKey(userID int) string {
+	return "mfa:" + strconv.Itoa(userID)
+}
+
+// IPKey returns the throttle key for attempts from a client IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// isIPKey reports whether key throttles a client IP rather than an account or user
+func isIPKey(key string) bool {
+	return strings.HasPrefix(key, "ip:")
+}
+
+// Reserve counts an attempt against the keys before its credentials are checked, and returns a
+// ThrottledError if any key is locked, has reached its threshold or is still within its
+// progressive delay. Each attempt is counted by a single statement and judged by the count it
+// returns, so parallel attempts cannot all pass on the same state. Refused attempts are given
+// back; allowed ones must end with RecordFailure or RecordSuccess.
+func (g *LoginGuard) Reserve(ctx context.Context, keys ...string) error {
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(g.cfg.FailureWindow) * time.Minute)
+
+	var worst *ThrottledError
+	var reserved []string
+	for _, key := range keys {
+		t, err := g.repo.Reserve(ctx, key, now, windowStart)
+		if err != nil {
+			g.release(ctx, reserved...)
+			return err
+		}
+
+		var throttled *ThrottledError
+		if t.LockedUntil != nil && t.LockedUntil.After(now) {
+			throttled = &ThrottledError{RetryAfter: t.LockedUntil.Sub(now), Locked: true}
+		} else {
+			reserved = append(reserved, key)
+
+			// Failures before this attempt, including attempts still being checked
+			previous := t.Failures - 1
+			limit, _ := g.threshold(key)
+			if limit > 0 && previous >= limit {
+				// The attempt that reached the threshold is locking the key
+				throttled = &ThrottledError{RetryAfter: time.Duration(g.cfg.Duration) * time.Minute, Locked: true}
+			} else if delay := g.delay(previous); delay > 0 && t.LastFailureAt.Add(delay).After(now) {
+				throttled = &ThrottledError{RetryAfter: t.LastFailureAt.Add(delay).Sub(now)}
+			}
+		}
+
+		if throttled != nil && (worst == nil || throttled.RetryAfter > worst.RetryAfter) {
+			worst = throttled
+		}
+	}
+
+	if worst != nil {
+		g.release(ctx, reserved...)
+		return worst
+	}
+	return nil
+}
+
+// RecordFailure keeps the attempt reserved against the keys as a failure, locking any that
+// reach their threshold
+func (g *LoginGuard) RecordFailure(ctx context.Context, userID *int, ip string, keys ...string) error {
+	now := time.Now()
+
+	for _, key := range keys {
+		t, err := g.repo.RecordFailure(ctx, key, now)
+		if err != nil {
+			return err
+		}
+
+		limit, eventType := g.threshold(key)
+		if t == nil || limit <= 0 || t.Failures < limit {
+			continue
+		}
+
+		until := now.Add(time.Duration(g.cfg.Duration) * time.Minute)
+		if err := g.repo.Lock(ctx, key, until); err != nil {
+			return err
+		}
+
+		event := models.AuditEvent{
+			Type: eventType,
+			IP:   ip,
+			Details: map[string]interface{}{
+				"key":          key,
+				"failures":     t.Failures,
+				"locked_until": until,
+			},
+		}
+		if eventType == models.AuditAccountLocked {
+			event.UserID = userID
+		}
+		g.record(ctx, event)
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the failure history of the account and MFA keys after a successful
+// attempt. IP keys keep theirs, only giving back the attempt reserved against them.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if isIPKey(key) {
+			if err := g.repo.Release(ctx, key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := g.repo.Clear(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlock clears lockouts for the user's keys on behalf of an admin and records an audit event
+func (g *LoginGuard) Unlock(ctx context.Context, userID, actorID int, keys ...string) error {
+	if err := g.RecordSuccess(ctx, keys...); err != nil {
+		return err
+	}
+
+	g.record(ctx, models.AuditEvent{
+		Type:    models.AuditAccountUnlocked,
+		UserID:  &userID,
+		ActorID: &actorID,
+		Details: map[string]interface{}{"keys": keys},
+	})
+
+	return nil
+}
+
+// threshold returns the failures that lock a key and the audit event recorded when they do
+func (g *LoginGuard) threshold(key string) (int, string) {
+	if isIPKey(key) {
+		return g.cfg.MaxIPFailures, models.AuditIPLocked
+	}
+	return g.cfg.MaxAccountFailures, models.AuditAccountLocked
+}
+
+// delay returns the wait required after the given number of consecutive failures,
+// doubling from the base delay up to the configured maximum
+func (g *LoginGuard) delay(failures int) time.Duration {
+	if failures <= 0 || g.cfg.BaseDelayMs <= 0 {
+		return 0
+	}
+
+	delay := time.Duration(g.cfg.BaseDelayMs) * time.Millisecond
+	max := time.Duration(g.cfg.MaxDelayMs) * time.Millisecond
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// release gives back the attempts reserved against the keys when they were refused or the request
+// ended before its credentials were checked. Errors are logged rather than returned: an attempt
+// left counted only makes the guard stricter.
+func (g *LoginGuard) release(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := g.repo.Release(ctx, key); err != nil {
+			g.log.WithContext(ctx).Error("Failed to release login attempt", "key", key, "error", err)
+		}
+	}
+}
+
+// record stores an audit event, logging it so lockouts are visible even if storage fails
+func (g *LoginGuard) record(ctx context.Context, event models.AuditEvent) {
+	g.log.WithContext(ctx).Warn("Audit event", "type", event.Type, "user_id", event.UserID, "actor_id", event.ActorID, "ip", event.IP, "details", event.Details)
+
+	if err := g.audit.Record(ctx, event); err != nil {
+		g.log.WithContext(ctx).Error("Failed to record audit event", "type", event.Type, "error", err)
+	}
+}
@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"goapi/internal/config"
+	"goapi/internal/database"
+	"goapi/internal/database/storetest"
+	"goapi/internal/services"
+	"goapi/pkg/logger"
+)
+
+func TestLoginGuardConcurrentAttempts(t *testing.T) {
+	db := storetest.Open(t, "sqlite")
+	log, err := logger.New(logger.Options{Level: "error", Output: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.LockoutConfig
+		// failures are recorded one after another before the concurrent attempts
+		failures    int
+		attempts    int
+		wantAllowed int
+		// wantLocked is whether refused attempts report a lockout rather than a delay
+		wantLocked bool
+	}{
+		{
+			name:        "attempts below the threshold all pass",
+			cfg:         config.LockoutConfig{MaxAccountFailures: 10, FailureWindow: 15, Duration: 15},
+			attempts:    5,
+			wantAllowed: 5,
+		},
+		{
+			name:        "concurrent attempts stop at the threshold",
+			cfg:         config.LockoutConfig{MaxAccountFailures: 3, FailureWindow: 15, Duration: 15},
+			attempts:    10,
+			wantAllowed: 3,
+			wantLocked:  true,
+		},
+		{
+			name:        "earlier failures count toward the threshold",
+			cfg:         config.LockoutConfig{MaxAccountFailures: 3, FailureWindow: 15, Duration: 15},
+			failures:    2,
+			attempts:    10,
+			wantAllowed: 1,
+			wantLocked:  true,
+		},
+		{
+			name:        "a locked account refuses every attempt",
+			cfg:         config.LockoutConfig{MaxAccountFailures: 2, FailureWindow: 15, Duration: 15},
+			failures:    2,
+			attempts:    10,
+			wantAllowed: 0,
+			wantLocked:  true,
+		},
+		{
+			name:        "attempts within the delay after a failure are refused",
+			cfg:         config.LockoutConfig{MaxAccountFailures: 10, FailureWindow: 15, Duration: 15, BaseDelayMs: 60000, MaxDelayMs: 60000},
+			failures:    1,
+			attempts:    10,
+			wantAllowed: 0,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			guard := services.NewLoginGuard(database.NewLockoutRepository(db), database.NewAuditRepository(db), tt.cfg, log)
+			key := services.AccountKey(i+1, "user@example.com")
+
+			for n := 0; n < tt.failures; n++ {
+				if err := guard.Reserve(ctx, key); err != nil {
+					t.Fatalf("failure %d refused: %v", n+1, err)
+				}
+				if err := guard.RecordFailure(ctx, nil, "", key); err != nil {
+					t.Fatalf("RecordFailure: %v", err)
+				}
+			}
+
+			var mu sync.Mutex
+			var wg sync.WaitGroup
+			allowed := 0
+			start := make(chan struct{})
+			for n := 0; n < tt.attempts; n++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					err := guard.Reserve(ctx, key)
+
+					mu.Lock()
+					defer mu.Unlock()
+					var throttled *services.ThrottledError
+					switch {
+					case err == nil:
+						allowed++
+					case !errors.As(err, &throttled):
+						t.Errorf("Reserve: %v", err)
+					case throttled.Locked != tt.wantLocked:
+						t.Errorf("refused with %v, want locked %v", err, tt.wantLocked)
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			if allowed != tt.wantAllowed {
+				t.Fatalf("%d of %d attempts allowed, want %d", allowed, tt.attempts, tt.wantAllowed)
+			}
+
+			// Refused attempts were given back, so each allowed one that fails counts once
+			for n := 0; n < allowed; n++ {
+				if err := guard.RecordFailure(ctx, nil, "", key); err != nil {
+					t.Fatalf("RecordFailure: %v", err)
+				}
+			}
+			limit := tt.cfg.MaxAccountFailures
+			err := guard.Reserve(ctx, key)
+			var throttled *services.ThrottledError
+			locked := errors.As(err, &throttled) && throttled.Locked
+			if wantLocked := tt.failures+allowed >= limit; locked != wantLocked {
+				t.Fatalf("after %d failures the next attempt got %v, want locked %v", tt.failures+allowed, err, wantLocked)
+			}
+		})
+	}
+}
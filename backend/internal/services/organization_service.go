@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"goapi/internal/database"
+	"goapi/internal/models"
+)
+
+var (
+	// ErrOrganizationNotFound is returned when an organization does not exist
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrSlugExists is returned when an organization slug is already taken
+	ErrSlugExists = errors.New("slug already exists")
+	// ErrTenantRequired is returned when a request cannot be tied to an organization
+	ErrTenantRequired = errors.New("organization required")
+)
+
+// OrganizationService handles organization business logic
+type OrganizationService struct {
+	orgRepo *database.OrganizationRepository
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(orgRepo *database.OrganizationRepository) *OrganizationService {
+	return &OrganizationService{
+		orgRepo: orgRepo,
+	}
+}
+
+// GetAllOrganizations retrieves all organizations
+func (s *OrganizationService) GetAllOrganizations(ctx context.Context) ([]models.Organization, error) {
+	orgs, err := s.orgRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+
+	if orgs == nil {
+		orgs = []models.Organization{}
+	}
+	return orgs, nil
+}
+
+// GetOrganizationByID retrieves an organization by ID
+func (s *OrganizationService) GetOrganizationByID(ctx context.Context, id int) (*models.Organization, error) {
+	org, err := s.orgRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, organizationError(err)
+	}
+	return org, nil
+}
+
+// CreateOrganization creates a new organization
+func (s *OrganizationService) CreateOrganization(ctx context.Context, req models.CreateOrganizationRequest) (*models.Organization, error) {
+	org, err := s.orgRepo.Create(ctx, req)
+	if err != nil {
+		return nil, organizationError(err)
+	}
+	return org, nil
+}
+
+// UpdateOrganization updates an existing organization
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, id int, req models.UpdateOrganizationRequest) (*models.Organization, error) {
+	org, err := s.orgRepo.Update(ctx, id, req)
+	if err != nil {
+		return nil, organizationError(err)
+	}
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization and its users
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, id int) error {
+	if err := s.orgRepo.Delete(ctx, id); err != nil {
+		return organizationError(err)
+	}
+	return nil
+}
+
+// ResolveTenant resolves an organization slug or numeric ID to an organization ID
+func (s *OrganizationService) ResolveTenant(ctx context.Context, ref string) (int, error) {
+	var (
+		org *models.Organization
+		err error
+	)
+
+	if id, convErr := strconv.Atoi(ref); convErr == nil {
+		org, err = s.orgRepo.GetByID(ctx, id)
+	} else {
+		org, err = s.orgRepo.GetBySlug(ctx, ref)
+	}
+
+	if err != nil {
+		return 0, organizationError(err)
+	}
+	return org.ID, nil
+}
+
+// organizationError maps repository errors to service errors
+func organizationError(err error) error {
+	switch {
+	case errors.Is(err, database.ErrSlugExists):
+		return ErrSlugExists
+	case errors.Is(err, database.ErrNotFound):
+		return ErrOrganizationNotFound
+	default:
+		return err
+	}
+}
@@ -1,27 +1,61 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"goapi/internal/auth"
 	"goapi/internal/database"
 	"goapi/internal/models"
+	"goapi/internal/tracing"
+	"goapi/pkg/logger"
 )
 
+var (
+	// ErrEmailExists is returned when an email address is already used in the organization
+	ErrEmailExists = errors.New("email already exists")
+	// ErrUserNotFound is returned when a user does not exist in the organization
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// UserStore persists users. It is implemented by database.UserRepository and by
+// database.CachedUserRepository, which caches lookups in front of it.
+type UserStore interface {
+	GetAll(ctx context.Context, orgID int) ([]models.User, error)
+	Stream(ctx context.Context, orgID int, fn func(*models.User) error) error
+	Version(ctx context.Context, orgID int) (models.UserListVersion, error)
+	GetByID(ctx context.Context, orgID, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, orgID int, email string) (*models.User, error)
+	Create(ctx context.Context, orgID int, req models.CreateUserRequest, passwordHash string) (*models.User, error)
+	CreateVerified(ctx context.Context, orgID int, req models.CreateUserRequest, passwordHash string, claim database.Claim) (*models.User, error)
+	Update(ctx context.Context, orgID, id int, req models.UpdateUserRequest) (*models.User, error)
+	Delete(ctx context.Context, orgID, id int) error
+	MarkEmailVerified(ctx context.Context, orgID, id int) error
+}
+
 // UserService handles user business logic
 type UserService struct {
-	userRepo *database.UserRepository
+	userRepo UserStore
+	accounts *AccountService
+	log      logger.Logger
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *database.UserRepository) *UserService {
+func NewUserService(userRepo UserStore, accounts *AccountService, log logger.Logger) *UserService {
 	return &UserService{
 		userRepo: userRepo,
+		accounts: accounts,
+		log:      log,
 	}
 }
 
-// GetAllUsers retrieves all users
-func (s *UserService) GetAllUsers() ([]models.UserResponse, error) {
-	users, err := s.userRepo.GetAll()
+// GetAllUsers retrieves all users in an organization
+func (s *UserService) GetAllUsers(ctx context.Context, orgID int) (_ []models.UserResponse, err error) {
+	ctx, end := tracing.Start(ctx, "UserService.GetAllUsers")
+	defer end(&err)
+
+	users, err := s.userRepo.GetAll(ctx, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -34,9 +68,37 @@ func (s *UserService) GetAllUsers() ([]models.UserResponse, error) {
 	return responses, nil
 }
 
-// GetUserByID retrieves a user by ID
-func (s *UserService) GetUserByID(id int) (*models.UserResponse, error) {
-	user, err := s.userRepo.GetByID(id)
+// StreamUsers calls fn with each user in an organization as it is read, so the list is never
+// held in memory. It stops at the first error fn returns.
+func (s *UserService) StreamUsers(ctx context.Context, orgID int, fn func(models.UserResponse) error) (err error) {
+	ctx, end := tracing.Start(ctx, "UserService.StreamUsers")
+	defer end(&err)
+
+	return s.userRepo.Stream(ctx, orgID, func(user *models.User) error {
+		return fn(user.ToResponse())
+	})
+}
+
+// GetUsersVersion returns the version of an organization's user list, which changes whenever
+// the list does
+func (s *UserService) GetUsersVersion(ctx context.Context, orgID int) (_ models.UserListVersion, err error) {
+	ctx, end := tracing.Start(ctx, "UserService.GetUsersVersion")
+	defer end(&err)
+
+	version, err := s.userRepo.Version(ctx, orgID)
+	if err != nil {
+		return models.UserListVersion{}, fmt.Errorf("failed to get users version: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetUserByID retrieves a user by ID within an organization
+func (s *UserService) GetUserByID(ctx context.Context, orgID, id int) (_ *models.UserResponse, err error) {
+	ctx, end := tracing.Start(ctx, "UserService.GetUserByID")
+	defer end(&err)
+
+	user, err := s.userRepo.GetByID(ctx, orgID, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -45,52 +107,125 @@ func (s *UserService) GetUserByID(id int) (*models.UserResponse, error) {
 	return &response, nil
 }
 
-// CreateUser creates a new user
-func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.UserResponse, error) {
-	// Validate email uniqueness
-	_, err := s.userRepo.GetByEmail(req.Email)
-	if err == nil {
-		return nil, fmt.Errorf("email already exists")
+// CreateUser creates a new user in an organization and sends a verification email
+func (s *UserService) CreateUser(ctx context.Context, orgID int, req models.CreateUserRequest) (_ *models.UserResponse, err error) {
+	ctx, end := tracing.Start(ctx, "UserService.CreateUser")
+	defer end(&err)
+
+	user, err := s.createUser(ctx, orgID, req, func(req models.CreateUserRequest, passwordHash string) (*models.User, error) {
+		return s.userRepo.Create(ctx, orgID, req, passwordHash)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := s.userRepo.Create(req)
+	s.sendVerificationEmail(ctx, orgID, user.ID)
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// CreateInvitedUser creates a user who accepted an emailed invitation, in one transaction with
+// claim, which marks the invitation accepted. Receiving the invitation proves ownership of the
+// address, so the email is stored as verified. Errors from claim are wrapped.
+func (s *UserService) CreateInvitedUser(ctx context.Context, orgID int, req models.CreateUserRequest, claim database.Claim) (_ *models.UserResponse, err error) {
+	ctx, end := tracing.Start(ctx, "UserService.CreateInvitedUser")
+	defer end(&err)
+
+	user, err := s.createUser(ctx, orgID, req, func(req models.CreateUserRequest, passwordHash string) (*models.User, error) {
+		return s.userRepo.CreateVerified(ctx, orgID, req, passwordHash, claim)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, err
 	}
 
 	response := user.ToResponse()
 	return &response, nil
 }
 
-// UpdateUser updates an existing user
-func (s *UserService) UpdateUser(id int, req models.UpdateUserRequest) (*models.UserResponse, error) {
+// createUser validates email uniqueness, defaults the role, hashes the password and stores the
+// user with store
+func (s *UserService) createUser(ctx context.Context, orgID int, req models.CreateUserRequest, store func(models.CreateUserRequest, string) (*models.User, error)) (*models.User, error) {
+	// Validate email uniqueness within the organization
+	_, err := s.userRepo.GetByEmail(ctx, orgID, req.Email)
+	if err == nil {
+		return nil, ErrEmailExists
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+
+	var passwordHash string
+	if req.Password != "" {
+		passwordHash, err = auth.HashPassword(req.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+	}
+
+	user, err := store(req, passwordHash)
+	if err != nil {
+		if err.Error() == "email already exists" {
+			return nil, ErrEmailExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser updates an existing user within an organization
+func (s *UserService) UpdateUser(ctx context.Context, orgID, id int, req models.UpdateUserRequest) (_ *models.UserResponse, err error) {
+	ctx, end := tracing.Start(ctx, "UserService.UpdateUser")
+	defer end(&err)
+
 	// Check if user exists
-	_, err := s.userRepo.GetByID(id)
+	current, err := s.userRepo.GetByID(ctx, orgID, id)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	// Check if email is being changed and if new email already exists
-	existingUser, err := s.userRepo.GetByEmail(req.Email)
+	existingUser, err := s.userRepo.GetByEmail(ctx, orgID, req.Email)
 	if err == nil && existingUser.ID != id {
 		return nil, fmt.Errorf("email already exists")
 	}
 
-	user, err := s.userRepo.Update(id, req)
+	user, err := s.userRepo.Update(ctx, orgID, id, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	// A changed email address must be verified again
+	if user.Email != current.Email {
+		s.sendVerificationEmail(ctx, orgID, user.ID)
+	}
+
 	response := user.ToResponse()
 	return &response, nil
 }
 
-// DeleteUser deletes a user
-func (s *UserService) DeleteUser(id int) error {
-	err := s.userRepo.Delete(id)
+// DeleteUser deletes a user within an organization
+func (s *UserService) DeleteUser(ctx context.Context, orgID, id int) (err error) {
+	ctx, end := tracing.Start(ctx, "UserService.DeleteUser")
+	defer end(&err)
+
+	err = s.userRepo.Delete(ctx, orgID, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
 	return nil
 }
+
+// sendVerificationEmail sends a verification email, logging rather than failing the caller on error
+func (s *UserService) sendVerificationEmail(ctx context.Context, orgID, userID int) {
+	if s.accounts == nil {
+		return
+	}
+
+	if err := s.accounts.SendVerificationEmail(ctx, orgID, userID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to send verification email", "org_id", orgID, "user_id", userID, "error", err)
+	}
+}
@@ -0,0 +1,25 @@
+package tenant
+
+import (
+	"context"
+)
+
+type contextKey struct{}
+
+// WithOrgID returns a copy of ctx scoped to the organization
+func WithOrgID(ctx context.Context, orgID int) context.Context {
+	return context.WithValue(ctx, contextKey{}, orgID)
+}
+
+// OrgIDFromContext returns the organization the request is scoped to, if any
+func OrgIDFromContext(ctx context.Context) (int, bool) {
+	orgID, ok := ctx.Value(contextKey{}).(int)
+	return orgID, ok && orgID > 0
+}
+
+// OrgID returns the organization the request is scoped to, or 0 when there is none.
+// No organization has ID 0, so queries scoped to it match nothing.
+func OrgID(ctx context.Context) int {
+	orgID, _ := OrgIDFromContext(ctx)
+	return orgID
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"context"
+)
+
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying fields that WithContext adds to log entries.
+// Fields accumulate, so nested calls add to the fields already present.
+func ContextWithFields(ctx context.Context, fields ...interface{}) context.Context {
+	existing := FieldsFromContext(ctx)
+	merged := make([]interface{}, 0, len(existing)+len(fields))
+	merged = append(merged, existing...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FieldsFromContext returns the log fields carried by ctx
+func FieldsFromContext(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return fields
+}
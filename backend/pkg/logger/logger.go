@@ -1,52 +1,138 @@
 package logger
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 )
 
-// Logger interface for structured logging
+// Logger interface for structured logging.
+// Fields are alternating key-value pairs, e.g. Info("user created", "user_id", 42).
 type Logger interface {
 	Info(msg string, fields ...interface{})
 	Error(msg string, fields ...interface{})
 	Debug(msg string, fields ...interface{})
 	Warn(msg string, fields ...interface{})
+
+	// With returns a child logger that adds fields to every entry
+	With(fields ...interface{}) Logger
+
+	// WithContext returns a child logger that adds the fields carried by ctx
+	WithContext(ctx context.Context) Logger
+
+	// Slog exposes the underlying slog.Logger for libraries that need one
+	Slog() *slog.Logger
 }
 
-// StandardLogger implements Logger interface using standard log package
+// Options configures a logger
+type Options struct {
+	// Level is the minimum level written: debug, info, warn or error
+	Level string
+	// Format selects the handler: json or text
+	Format string
+	// Output defaults to os.Stdout
+	Output io.Writer
+	// LevelVar, when set, holds the level so it can be changed while the logger is in use
+	LevelVar *slog.LevelVar
+}
+
+// StandardLogger implements Logger on top of log/slog
 type StandardLogger struct {
-	infoLogger  *log.Logger
-	errorLogger *log.Logger
-	debugLogger *log.Logger
-	warnLogger  *log.Logger
+	logger *slog.Logger
 }
 
-// NewLogger creates a new logger instance
+// New creates a logger from options, rejecting unknown levels and formats
+func New(opts Options) (Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	levelVar := opts.LevelVar
+	if levelVar == nil {
+		levelVar = new(slog.LevelVar)
+	}
+	levelVar.Set(level)
+
+	handlerOpts := &slog.HandlerOptions{Level: levelVar}
+
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(output, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(output, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	return &StandardLogger{logger: slog.New(handler)}, nil
+}
+
+// NewLogger creates a JSON logger at info level writing to stdout
 func NewLogger() Logger {
-	return &StandardLogger{
-		infoLogger:  log.New(os.Stdout, "INFO: ", log.LstdFlags|log.Lshortfile),
-		errorLogger: log.New(os.Stderr, "ERROR: ", log.LstdFlags|log.Lshortfile),
-		debugLogger: log.New(os.Stdout, "DEBUG: ", log.LstdFlags|log.Lshortfile),
-		warnLogger:  log.New(os.Stdout, "WARN: ", log.LstdFlags|log.Lshortfile),
+	return &StandardLogger{logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+}
+
+// ParseLevel converts a level name into a slog level
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
 	}
 }
 
 // Info logs info level messages
 func (l *StandardLogger) Info(msg string, fields ...interface{}) {
-	l.infoLogger.Printf(msg, fields...)
+	l.logger.Info(msg, fields...)
 }
 
 // Error logs error level messages
 func (l *StandardLogger) Error(msg string, fields ...interface{}) {
-	l.errorLogger.Printf(msg, fields...)
+	l.logger.Error(msg, fields...)
 }
 
 // Debug logs debug level messages
 func (l *StandardLogger) Debug(msg string, fields ...interface{}) {
-	l.debugLogger.Printf(msg, fields...)
+	l.logger.Debug(msg, fields...)
 }
 
 // Warn logs warning level messages
 func (l *StandardLogger) Warn(msg string, fields ...interface{}) {
-	l.warnLogger.Printf(msg, fields...)
+	l.logger.Warn(msg, fields...)
+}
+
+// With returns a child logger that adds fields to every entry
+func (l *StandardLogger) With(fields ...interface{}) Logger {
+	return &StandardLogger{logger: l.logger.With(fields...)}
+}
+
+// WithContext returns a child logger that adds the fields carried by ctx
+func (l *StandardLogger) WithContext(ctx context.Context) Logger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// Slog exposes the underlying slog.Logger
+func (l *StandardLogger) Slog() *slog.Logger {
+	return l.logger
 }
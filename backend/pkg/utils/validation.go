@@ -45,3 +45,9 @@ func ValidateUserInput(name, email string) []string {
 	
 	return errors
 }
+
+// IsValidSlug validates an organization slug (lowercase letters, digits and hyphens, 2-63 characters)
+func IsValidSlug(slug string) bool {
+	slugRegex := regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+	return slugRegex.MatchString(slug)
+}
@@ -40,4 +40,4 @@ echo "Health check: http://localhost:8080/health"
 echo "Press Ctrl+C to stop the server"
 echo ""
 
-go run cmd/api/main.go
+go run ./cmd/api
@@ -25,7 +25,9 @@ export interface ApiError {
 const api = axios.create({
   baseURL: 'http://localhost:8080/api',
   headers: {
-    'Content-Type': 'application/json'
+    'Content-Type': 'application/json',
+    // Selects the tenant for unauthenticated requests; only honored when the API runs with APP_ENV=development
+    'X-Tenant': import.meta.env.VITE_TENANT ?? 'default'
   }
 })
 